@@ -12,10 +12,22 @@ import (
 	"strings"
 
 	"github.com/kubefirst/kubefirst-api/pkg/reports"
+	"github.com/kubefirst/kubefirst/internal/progress"
 	awsinternal "github.com/kubefirst/runtime/pkg/aws"
 	"github.com/spf13/cobra"
 )
 
+// awsQuotaReport is the structured form of the aws quota health report
+type awsQuotaReport struct {
+	Region   string                `json:"region" yaml:"region"`
+	Services map[string][]awsQuota `json:"services" yaml:"services"`
+}
+
+type awsQuota struct {
+	Name  string  `json:"name" yaml:"name"`
+	Value float64 `json:"value" yaml:"value"`
+}
+
 // printAwsQuotaWarning provides visual output detailing quota health for aws
 func printAwsQuotaWarning(messageHeader string, output map[string][]awsinternal.QuotaDetailResponse) string {
 	var createAwsQuotaWarning bytes.Buffer
@@ -53,6 +65,23 @@ func evalAwsQuota(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if progress.IsStructuredOutput() {
+		report := awsQuotaReport{
+			Region:   cloudRegionFlag,
+			Services: map[string][]awsQuota{},
+		}
+		for service, quotas := range quotaDetails {
+			for _, quota := range quotas {
+				report.Services[service] = append(report.Services[service], awsQuota{
+					Name:  quota.QuotaName,
+					Value: quota.QuotaValue,
+				})
+			}
+		}
+
+		return progress.Document(report)
+	}
+
 	var messageHeader = fmt.Sprintf(
 		"AWS Quota Health\nRegion: %s\n\nIf you encounter issues deploying your kubefirst cluster, check these quotas and determine if you need to request a limit increase.",
 		cloudRegionFlag,
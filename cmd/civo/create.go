@@ -7,6 +7,7 @@ See the LICENSE file for more details.
 package civo
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -16,7 +17,6 @@ import (
 	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/kubefirst/kubefirst/internal/provision"
 	"github.com/kubefirst/kubefirst/internal/utilities"
-	"github.com/kubefirst/runtime/pkg"
 	internalssh "github.com/kubefirst/runtime/pkg/ssh"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -30,7 +30,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	progress.DisplayLogHints(15)
+	progress.DisplayLogHints(progress.EstimatedMinutes("civo", 15))
 
 	err = ValidateProvidedFlags(cliFlags.GitProvider)
 	if err != nil {
@@ -48,7 +48,7 @@ func createCivo(cmd *cobra.Command, args []string) error {
 
 	utilities.CreateK1ClusterDirectory(clusterNameFlag)
 
-	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags.GitProvider, cliFlags.GithubOrg, cliFlags.GitlabGroup)
+	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags)
 
 	if err != nil {
 		progress.Error(err.Error())
@@ -58,20 +58,18 @@ func createCivo(cmd *cobra.Command, args []string) error {
 	// Validate git
 	executionControl := viper.GetBool(fmt.Sprintf("kubefirst-checks.%s-credentials", cliFlags.GitProvider))
 	if !executionControl {
-		newRepositoryNames := []string{"gitops", "metaphor"}
-		newTeamNames := []string{"admins", "developers"}
-
+		// the kubefirst api only creates the default repository and team names
 		initGitParameters := gitShim.GitInitParameters{
 			GitProvider:  cliFlags.GitProvider,
 			GitToken:     gitAuth.Token,
 			GitOwner:     gitAuth.Owner,
-			Repositories: newRepositoryNames,
-			Teams:        newTeamNames,
+			Repositories: utilities.DefaultGitNames.Repositories(),
+			Teams:        utilities.DefaultGitNames.Teams(),
 		}
 
 		err = gitShim.InitializeGitProvider(&initGitParameters)
 		if err != nil {
-			progress.Error(err.Error())
+			gitShim.DisplayInitError(err)
 			return nil
 		}
 	}
@@ -80,12 +78,19 @@ func createCivo(cmd *cobra.Command, args []string) error {
 
 	k3dClusterCreationComplete := viper.GetBool("launch.deployed")
 	if !k3dClusterCreationComplete {
-		launch.Up(nil, true, cliFlags.UseTelemetry)
+		launch.Up(launch.UpOptions{InCluster: true, UseTelemetry: cliFlags.UseTelemetry})
 	}
 
-	err = pkg.IsAppAvailable(fmt.Sprintf("%s/api/proxyHealth", cluster.GetConsoleIngresUrl()), "kubefirst api")
+	consoleClient, err := cluster.NewDefaultConsoleClient()
 	if err != nil {
-		progress.Error("unable to start kubefirst api")
+		progress.Error(err.Error())
+		return nil
+	}
+
+	err = consoleClient.WaitForHealthy(context.Background(), 60)
+	if err != nil {
+		progress.Error(fmt.Sprintf("unable to start kubefirst api: %s", err))
+		return nil
 	}
 
 	provision.CreateMgmtCluster(gitAuth, cliFlags)
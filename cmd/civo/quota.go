@@ -18,6 +18,7 @@ import (
 	"github.com/civo/civogo"
 	"github.com/fatih/color"
 	"github.com/kubefirst/kubefirst-api/pkg/reports"
+	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
@@ -68,19 +69,39 @@ type quotaFormattedOutput struct {
 	limitValue       float64
 }
 
+// civoQuotaReport is the structured form of the civo quota health report
+type civoQuotaReport struct {
+	Region   string           `json:"region" yaml:"region"`
+	Failures int              `json:"failures" yaml:"failures"`
+	Warnings int              `json:"warnings" yaml:"warnings"`
+	Quotas   []civoQuotaUsage `json:"quotas" yaml:"quotas"`
+}
+
+type civoQuotaUsage struct {
+	Name    string  `json:"name" yaml:"name"`
+	Usage   float64 `json:"usage" yaml:"usage"`
+	Limit   float64 `json:"limit" yaml:"limit"`
+	Percent float64 `json:"percent" yaml:"percent"`
+}
+
 // returnCivoQuotaEvaluation fetches quota from civo and compares limits to usage
-func returnCivoQuotaEvaluation(cloudRegion string) (string, int, int, error) {
+func returnCivoQuotaEvaluation(cloudRegion string) (string, civoQuotaReport, error) {
+	report := civoQuotaReport{
+		Region: cloudRegion,
+		Quotas: []civoQuotaUsage{},
+	}
+
 	// Fetch quota from civo
 	client, err := civogo.NewClient(os.Getenv("CIVO_TOKEN"), cloudRegion)
 	if err != nil {
 		log.Info().Msg(err.Error())
-		return "", 0, 0, err
+		return "", report, err
 	}
 
 	quota, err := client.GetQuota()
 	if err != nil {
 		log.Info().Msgf("failed to fetch civo quota: %s", err)
-		return "", 0, 0, err
+		return "", report, err
 
 	}
 
@@ -91,12 +112,12 @@ func returnCivoQuotaEvaluation(cloudRegion string) (string, int, int, error) {
 	quotaJSON, err := json.Marshal(quota)
 	if err != nil {
 		log.Info().Msgf("failed to marshal civo quota struct: %s", err)
-		return "", 0, 0, err
+		return "", report, err
 	}
 	err = json.Unmarshal(quotaJSON, &quotaMap)
 	if err != nil {
 		log.Info().Msgf("failed to unmarshal civo quota struct: %s", err)
-		return "", 0, 0, err
+		return "", report, err
 	}
 
 	// Compare actual to limit and warn against threshold
@@ -115,6 +136,12 @@ func returnCivoQuotaEvaluation(cloudRegion string) (string, int, int, error) {
 			actualFieldValue: quotaMap[actualField].(float64),
 			limitValue:       quotaMap[limitField].(float64),
 		}
+		report.Quotas = append(report.Quotas, civoQuotaUsage{
+			Name:    actualField,
+			Usage:   checkObj.actualFieldValue,
+			Limit:   checkObj.limitValue,
+			Percent: percentCalc,
+		})
 
 		switch {
 		case percentCalc > quotaObjectThresholdWarning && percentCalc < quotaObjectThresholdCritical:
@@ -136,7 +163,13 @@ func returnCivoQuotaEvaluation(cloudRegion string) (string, int, int, error) {
 	sort.Strings(output)
 	result := printCivoQuotaWarning(messageHeader, output)
 
-	return result, quotaFailures, quotaWarnings, nil
+	sort.Slice(report.Quotas, func(i, j int) bool {
+		return report.Quotas[i].Name < report.Quotas[j].Name
+	})
+	report.Failures = quotaFailures
+	report.Warnings = quotaWarnings
+
+	return result, report, nil
 }
 
 // formatQuotaOutput returns a formatted string representation of a specific quota comparison
@@ -179,11 +212,15 @@ func evalCivoQuota(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	message, _, _, err := returnCivoQuotaEvaluation(cloudRegionFlag)
+	message, report, err := returnCivoQuotaEvaluation(cloudRegionFlag)
 	if err != nil {
 		return err
 	}
 
+	if progress.IsStructuredOutput() {
+		return progress.Document(report)
+	}
+
 	// Write to logs, but also output to stdout
 	fmt.Println(reports.StyleMessage(message))
 
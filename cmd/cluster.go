@@ -0,0 +1,47 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/provision"
+	"github.com/spf13/cobra"
+)
+
+func ClusterCommand() *cobra.Command {
+	clusterCommand := &cobra.Command{
+		Use:   "cluster",
+		Short: "interact with clusters known to the kubefirst console",
+		Long:  "interact with clusters known to the kubefirst console",
+	}
+
+	// wire up new commands
+	clusterCommand.AddCommand(clusterWatch())
+
+	return clusterCommand
+}
+
+// clusterWatch attaches to the provisioning progress of a cluster
+func clusterWatch() *cobra.Command {
+	clusterWatchCmd := &cobra.Command{
+		Use:              "watch",
+		Short:            "follow the provisioning progress of a cluster, exits non-zero if provisioning fails",
+		TraverseChildren: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("you must provide a cluster name as the only argument to this command")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			provision.WatchCluster(args[0])
+		},
+	}
+
+	return clusterWatchCmd
+}
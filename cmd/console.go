@@ -0,0 +1,206 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Console context
+	consoleURLFlag      string
+	consoleCABundleFlag string
+	consoleInsecureFlag bool
+	consoleTokenFlag    string
+)
+
+func ConsoleCommand() *cobra.Command {
+	consoleCommand := &cobra.Command{
+		Use:   "console",
+		Short: "manage the kubefirst console endpoints used by the cli",
+		Long:  "manage the kubefirst console endpoints used by the cli",
+	}
+
+	// wire up new commands
+	consoleCommand.AddCommand(consoleContext())
+
+	return consoleCommand
+}
+
+// consoleContext groups the commands that manage named console contexts
+func consoleContext() *cobra.Command {
+	consoleContextCmd := &cobra.Command{
+		Use:              "context",
+		Short:            "manage named console contexts, similar to kubeconfig contexts",
+		TraverseChildren: true,
+	}
+
+	consoleContextCmd.AddCommand(consoleContextAdd(), consoleContextUse(), consoleContextList(), consoleContextRemove())
+
+	return consoleContextCmd
+}
+
+// consoleContextAdd stores a new console context in the kubefirst config
+func consoleContextAdd() *cobra.Command {
+	consoleContextAddCmd := &cobra.Command{
+		Use:   "add",
+		Short: "add or replace a console context",
+		Long: `add or replace a console context, the name may only contain lowercase letters,
+digits and '-'. the context is stored in the kubefirst config, which holds the --token in
+plaintext and is written readable by the current user only.`,
+		TraverseChildren: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("you must provide a context name as the only argument to this command")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cluster.AddConsoleContext(cluster.ConsoleContext{
+				Name:     args[0],
+				URL:      consoleURLFlag,
+				CABundle: consoleCABundleFlag,
+				Insecure: consoleInsecureFlag,
+				Token:    consoleTokenFlag,
+			})
+			if err != nil {
+				progress.Error(fmt.Sprintf("error adding console context: %s", err))
+				return
+			}
+
+			progress.Success(`
+##
+### Added console context` + fmt.Sprintf("`%s`", args[0]) + `
+### :bulb: - switch to it with ` + fmt.Sprintf("`kubefirst console context use %s`", args[0]) + `
+`)
+		},
+	}
+
+	consoleContextAddCmd.Flags().StringVar(&consoleURLFlag, "url", "", "the console url, e.g. https://console.example.com (required)")
+	consoleContextAddCmd.MarkFlagRequired("url")
+	consoleContextAddCmd.Flags().StringVar(&consoleCABundleFlag, "ca-bundle", "", "path to a pem encoded ca bundle used to verify the console certificate")
+	consoleContextAddCmd.Flags().BoolVar(&consoleInsecureFlag, "insecure", false, "skip verification of the console certificate")
+	consoleContextAddCmd.Flags().StringVar(&consoleTokenFlag, "token", "", "bearer token sent with every request to the console - stored in plaintext in the kubefirst config")
+
+	return consoleContextAddCmd
+}
+
+// consoleContextUse switches the active console context
+func consoleContextUse() *cobra.Command {
+	consoleContextUseCmd := &cobra.Command{
+		Use:              "use",
+		Short:            "set the active console context",
+		TraverseChildren: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("you must provide a context name as the only argument to this command")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cluster.UseConsoleContext(args[0])
+			if err != nil {
+				progress.Error(fmt.Sprintf("error switching console context: %s", err))
+				return
+			}
+
+			progress.Success(`
+##
+### Switched to console context` + fmt.Sprintf("`%s`", args[0]) + `
+`)
+		},
+	}
+
+	return consoleContextUseCmd
+}
+
+// consoleContextList prints every known console context
+func consoleContextList() *cobra.Command {
+	consoleContextListCmd := &cobra.Command{
+		Use:              "list",
+		Short:            "list console contexts",
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			contexts, err := cluster.GetConsoleContexts()
+			if err != nil {
+				progress.Error(err.Error())
+				return
+			}
+
+			currentContext := cluster.GetCurrentConsoleContextName()
+
+			content := `
+| CURRENT | NAME | URL | TLS | TOKEN |
+| --- | --- | --- | --- | --- |
+`
+			for _, consoleContext := range contexts {
+				current := ""
+				if consoleContext.Name == currentContext {
+					current = "*"
+				}
+
+				tlsMode := "system"
+				switch {
+				case consoleContext.Insecure:
+					tlsMode = "insecure"
+				case consoleContext.CABundle != "":
+					tlsMode = consoleContext.CABundle
+				}
+
+				token := "no"
+				if consoleContext.Token != "" {
+					token = "yes"
+				}
+
+				content = content + fmt.Sprintf("|%s|%s|`%s`|%s|%s\n",
+					current,
+					consoleContext.Name,
+					consoleContext.URL,
+					tlsMode,
+					token,
+				)
+			}
+
+			progress.Success(content)
+		},
+	}
+
+	return consoleContextListCmd
+}
+
+// consoleContextRemove deletes a console context from the kubefirst config
+func consoleContextRemove() *cobra.Command {
+	consoleContextRemoveCmd := &cobra.Command{
+		Use:              "remove",
+		Short:            "remove a console context",
+		TraverseChildren: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("you must provide a context name as the only argument to this command")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			err := cluster.RemoveConsoleContext(args[0])
+			if err != nil {
+				progress.Error(fmt.Sprintf("error removing console context: %s", err))
+				return
+			}
+
+			progress.Success(`
+##
+### Removed console context` + fmt.Sprintf("`%s`", args[0]) + `
+`)
+		},
+	}
+
+	return consoleContextRemoveCmd
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 
 	"github.com/kubefirst/kubefirst/internal/common"
+	"github.com/kubefirst/kubefirst/internal/gitShim"
+	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +24,8 @@ var (
 	clusterTypeFlag          string
 	dnsProviderFlag          string
 	domainNameFlag           string
+	eventsFileFlag           string
+	eventsFormatFlag         string
 	googleProjectFlag        string
 	githubOrgFlag            string
 	gitlabGroupFlag          string
@@ -39,7 +43,6 @@ var (
 
 	// Supported providers
 	supportedDNSProviders = []string{"google", "cloudflare"}
-	supportedGitProviders = []string{"github", "gitlab"}
 
 	// Supported git providers
 	supportedGitProtocolOverride = []string{"https", "ssh"}
@@ -80,9 +83,11 @@ func Create() *cobra.Command {
 	createCmd.Flags().StringVar(&dnsProviderFlag, "dns-provider", "google", fmt.Sprintf("the dns provider - one of: %s", supportedDNSProviders))
 	createCmd.Flags().StringVar(&domainNameFlag, "domain-name", "", "the GCP DNS Name to use for DNS records (i.e. your-domain.com|subdomain.your-domain.com) (required)")
 	createCmd.MarkFlagRequired("domain-name")
+	createCmd.Flags().StringVar(&eventsFileFlag, "events-file", "", "write provisioning events to this file (optional)")
+	createCmd.Flags().StringVar(&eventsFormatFlag, "events-format", progress.EventsFormatNDJSON, "the format of the events file - one of: [ndjson]")
 	createCmd.Flags().StringVar(&googleProjectFlag, "google-project", "", "google project id (required)")
 	createCmd.MarkFlagRequired("google-project")
-	createCmd.Flags().StringVar(&gitProviderFlag, "git-provider", "github", fmt.Sprintf("the git provider - one of: %s", supportedGitProviders))
+	createCmd.Flags().StringVar(&gitProviderFlag, "git-provider", "github", fmt.Sprintf("the git provider - one of: %s", gitShim.SupportedGitProviders))
 	createCmd.Flags().StringVar(&gitProtocolFlag, "git-protocol", "ssh", fmt.Sprintf("the git protocol - one of: %s", supportedGitProtocolOverride))
 	createCmd.Flags().StringVar(&githubOrgFlag, "github-org", "", "the GitHub organization for the new gitops and metaphor repositories - required if using github")
 	createCmd.Flags().StringVar(&gitlabGroupFlag, "gitlab-group", "", "the GitLab group for the new gitops and metaphor projects - required if using gitlab")
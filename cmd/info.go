@@ -14,6 +14,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// infoSummary is the structured form of the info table
+type infoSummary struct {
+	OperationalSystem   string `json:"operational_system" yaml:"operational_system"`
+	Architecture        string `json:"architecture" yaml:"architecture"`
+	GoVersion           string `json:"go_version" yaml:"go_version"`
+	KubefirstConfigFile string `json:"kubefirst_config_file" yaml:"kubefirst_config_file"`
+	KubefirstConfigDir  string `json:"kubefirst_config_folder" yaml:"kubefirst_config_folder"`
+	KubefirstVersion    string `json:"kubefirst_version" yaml:"kubefirst_version"`
+}
+
 // infoCmd represents the info command
 var infoCmd = &cobra.Command{
 	Use:   "info",
@@ -23,6 +33,21 @@ var infoCmd = &cobra.Command{
 
 		config := configs.ReadConfig()
 
+		if progress.IsStructuredOutput() {
+			err := progress.Document(infoSummary{
+				OperationalSystem:   config.LocalOs,
+				Architecture:        config.LocalArchitecture,
+				GoVersion:           runtime.Version(),
+				KubefirstConfigFile: config.KubefirstConfigFilePath,
+				KubefirstConfigDir:  config.K1FolderPath,
+				KubefirstVersion:    configs.K1Version,
+			})
+			if err != nil {
+				progress.Error(err.Error())
+			}
+			return
+		}
+
 		content := `
 ##
 # Info summary
@@ -0,0 +1,44 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k3d
+
+import (
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/steps"
+	"github.com/spf13/viper"
+)
+
+// displayK3dPlan prints the plan without making any change
+func displayK3dPlan(plan []steps.Plan) error {
+	if progress.IsStructuredOutput() {
+		return progress.Document(plan)
+	}
+
+	content := `
+##
+# k3d create plan
+
+| STEP | STATUS | DESCRIPTION |
+| --- | --- | --- |
+`
+	for _, step := range plan {
+		content = content + fmt.Sprintf("|%s|%s|%s|\n", step.Name, step.Status, step.Description)
+	}
+
+	if viper.GetBool("kubefirst-checks.create-k3d-cluster-failed") {
+		content = content + "\n### :warning: the previous attempt to create the k3d cluster failed\n"
+	}
+	if viper.GetBool("kubefirst-checks.cluster-install-complete") {
+		content = content + "\n### :bulb: this install has completed, use `--from-step` to run steps again\n"
+	}
+
+	progress.Success(content)
+
+	return nil
+}
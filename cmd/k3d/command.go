@@ -9,24 +9,37 @@ package k3d
 import (
 	"fmt"
 
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/utilities"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Create
+	adminTeamNameFlag        string
 	applicationNameFlag      string
 	applicationNamespaceFlag string
+	bundleFlag               string
 	ciFlag                   bool
 	cloudRegionFlag          string
 	clusterNameFlag          string
 	clusterTypeFlag          string
+	developerTeamNameFlag    string
+	eventsFileFlag           string
+	eventsFormatFlag         string
+	fromStepFlag             string
 	githubUserFlag           string
 	githubOrgFlag            string
 	gitlabGroupFlag          string
+	gitNamePrefixFlag        string
 	gitProviderFlag          string
 	gitProtocolFlag          string
 	gitopsTemplateURLFlag    string
+	gitopsRepoNameFlag       string
 	gitopsTemplateBranchFlag string
+	metaphorRepoNameFlag     string
+	planFlag                 bool
+	rollbackOnFailureFlag    bool
 	useTelemetryFlag         bool
 
 	// RootCredentials
@@ -76,16 +89,27 @@ func Create() *cobra.Command {
 	}
 
 	// todo review defaults and update descriptions
+	createCmd.Flags().StringVar(&adminTeamNameFlag, "admin-team-name", utilities.DefaultAdminTeamName, "the name of the team created for platform admins")
+	createCmd.Flags().StringVar(&bundleFlag, "bundle", "", "path to a bundle created with kubefirst tools bundle to install the tools, manifests and gitops template from instead of downloading them")
 	createCmd.Flags().BoolVar(&ciFlag, "ci", false, "if running kubefirst in ci, set this flag to disable interactive features")
 	createCmd.Flags().StringVar(&clusterNameFlag, "cluster-name", "kubefirst", "the name of the cluster to create")
 	createCmd.Flags().StringVar(&clusterTypeFlag, "cluster-type", "mgmt", "the type of cluster to create (i.e. mgmt|workload)")
+	createCmd.Flags().StringVar(&developerTeamNameFlag, "developer-team-name", utilities.DefaultDeveloperTeamName, "the name of the team created for developers")
+	createCmd.Flags().StringVar(&eventsFileFlag, "events-file", "", "write provisioning events to this file (optional)")
+	createCmd.Flags().StringVar(&eventsFormatFlag, "events-format", progress.EventsFormatNDJSON, "the format of the events file - one of: [ndjson]")
+	createCmd.Flags().StringVar(&fromStepFlag, "from-step", "", "clear the check of this step and every step after it so they run again (see --plan for step names)")
+	createCmd.Flags().StringVar(&gitNamePrefixFlag, "git-name-prefix", "", "a prefix for the names of the new repositories and teams (i.e. prod- for prod-gitops)")
 	createCmd.Flags().StringVar(&gitProviderFlag, "git-provider", "github", fmt.Sprintf("the git provider - one of: %s", supportedGitProviders))
 	createCmd.Flags().StringVar(&gitProtocolFlag, "git-protocol", "ssh", fmt.Sprintf("the git protocol - one of: %s", supportedGitProtocolOverride))
 	createCmd.Flags().StringVar(&githubUserFlag, "github-user", "", "the GitHub user for the new gitops and metaphor repositories - this cannot be used with --github-org")
 	createCmd.Flags().StringVar(&githubOrgFlag, "github-org", "", "the GitHub organization for the new gitops and metaphor repositories - this cannot be used with --github-user")
 	createCmd.Flags().StringVar(&gitlabGroupFlag, "gitlab-group", "", "the GitLab group for the new gitops and metaphor projects - required if using gitlab")
+	createCmd.Flags().StringVar(&gitopsRepoNameFlag, "gitops-repo-name", utilities.DefaultGitopsRepoName, "the name of the new gitops repository")
 	createCmd.Flags().StringVar(&gitopsTemplateBranchFlag, "gitops-template-branch", "", "the branch to clone for the gitops-template repository")
 	createCmd.Flags().StringVar(&gitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to clone")
+	createCmd.Flags().StringVar(&metaphorRepoNameFlag, "metaphor-repo-name", utilities.DefaultMetaphorRepoName, "the name of the new metaphor repository")
+	createCmd.Flags().BoolVar(&planFlag, "plan", false, "print which steps would be skipped, re-run or are pending without making any change")
+	createCmd.Flags().BoolVar(&rollbackOnFailureFlag, "rollback-on-failure", false, "undo the completed steps when a step fails and report what is left over")
 	createCmd.Flags().BoolVar(&useTelemetryFlag, "use-telemetry", true, "whether to emit telemetry")
 
 	return createCmd
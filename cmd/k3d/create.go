@@ -8,32 +8,26 @@ package k3d
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/dustin/go-humanize"
 	"github.com/rs/zerolog/log"
 
-	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
-	"github.com/go-git/go-git/v5"
 	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/kubefirst/kubefirst-api/pkg/handlers"
 	"github.com/kubefirst/kubefirst-api/pkg/reports"
 	"github.com/kubefirst/kubefirst-api/pkg/wrappers"
-	"github.com/kubefirst/kubefirst/internal/gitShim"
+	"github.com/kubefirst/kubefirst/internal/bundle"
+	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/kubefirst/kubefirst/internal/segment"
 	"github.com/kubefirst/kubefirst/internal/utilities"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
 	"github.com/kubefirst/runtime/configs"
 	"github.com/kubefirst/runtime/pkg"
-	"github.com/kubefirst/runtime/pkg/argocd"
-	"github.com/kubefirst/runtime/pkg/gitClient"
 	"github.com/kubefirst/runtime/pkg/github"
 	gitlab "github.com/kubefirst/runtime/pkg/gitlab"
 	"github.com/kubefirst/runtime/pkg/helpers"
@@ -42,12 +36,9 @@ import (
 	"github.com/kubefirst/runtime/pkg/progressPrinter"
 	"github.com/kubefirst/runtime/pkg/services"
 	internalssh "github.com/kubefirst/runtime/pkg/ssh"
-	"github.com/kubefirst/runtime/pkg/terraform"
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"golang.org/x/exp/slices"
 )
 
 var (
@@ -89,6 +80,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	gitProviderFlag = resolveGitProvider(cmd, gitProviderFlag)
 
 	gitProtocolFlag, err := cmd.Flags().GetString("git-protocol")
 	if err != nil {
@@ -110,12 +102,91 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	planFlag, err := cmd.Flags().GetBool("plan")
+	if err != nil {
+		return err
+	}
+
+	eventsFileFlag, err := cmd.Flags().GetString("events-file")
+	if err != nil {
+		return err
+	}
+
+	eventsFormatFlag, err := cmd.Flags().GetString("events-format")
+	if err != nil {
+		return err
+	}
+
+	fromStepFlag, err := cmd.Flags().GetString("from-step")
+	if err != nil {
+		return err
+	}
+
+	rollbackOnFailureFlag, err := cmd.Flags().GetBool("rollback-on-failure")
+	if err != nil {
+		return err
+	}
+
+	bundleFlag, err := cmd.Flags().GetString("bundle")
+	if err != nil {
+		return err
+	}
+
+	gitNames, err := utilities.GetGitNames(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Report the state of every checkpoint without making any change
+	if planFlag {
+		plan, err := newK3dInstallGraph(&k3dInstall{gitProvider: gitProviderFlag}, nil).Plan(fromStepFlag)
+		if err != nil {
+			return err
+		}
+
+		return displayK3dPlan(plan)
+	}
+
+	// Clear the chosen checkpoint and everything after it so those steps run again
+	if fromStepFlag != "" {
+		err = newK3dInstallGraph(&k3dInstall{gitProvider: gitProviderFlag}, nil).ClearFrom(fromStepFlag)
+		if err != nil {
+			return err
+		}
+		viper.Set("kubefirst-checks.create-k3d-cluster-failed", false)
+		viper.WriteConfig()
+		log.Info().Msgf("cleared kubefirst checks from step %s", fromStepFlag)
+	}
+
 	// If cluster setup is complete, return
 	clusterSetupComplete := viper.GetBool("kubefirst-checks.cluster-install-complete")
 	if clusterSetupComplete {
 		return fmt.Errorf("this cluster install process has already completed successfully")
 	}
 
+	if eventsFileFlag != "" {
+		err = progress.OpenEventsFile(eventsFileFlag, eventsFormatFlag)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The bundle replaces the tool downloads, the argocd and vault manifests and the gitops template
+	var toolsBundle *bundle.Bundle
+	if bundleFlag != "" {
+		toolsBundle, err = bundle.Open(bundleFlag)
+		if err != nil {
+			return err
+		}
+		defer toolsBundle.Close()
+
+		if cmd.Flags().Changed("gitops-template-url") || cmd.Flags().Changed("gitops-template-branch") {
+			log.Warn().Msgf("using the gitops template from bundle %s instead of the gitops-template flags", bundleFlag)
+		}
+		gitopsTemplateURLFlag = toolsBundle.Manifest.GitopsTemplate.URL
+		gitopsTemplateBranchFlag = toolsBundle.Manifest.GitopsTemplate.Branch
+	}
+
 	utilities.CreateK1ClusterDirectory(clusterNameFlag)
 	helpers.DisplayLogHints()
 
@@ -310,6 +381,11 @@ func runK3d(cmd *cobra.Command, args []string) error {
 
 	// Instantiate K3d config
 	config := k3d.GetConfig(clusterNameFlag, gitProviderFlag, cGitOwner, gitProtocolFlag)
+	// GetConfig always names the repositories gitops and metaphor
+	config.DestinationGitopsRepoURL = fmt.Sprintf("https://%s/%s/%s.git", cGitHost, cGitOwner, gitNames.GitopsRepo)
+	config.DestinationGitopsRepoGitURL = fmt.Sprintf("git@%s:%s/%s.git", cGitHost, cGitOwner, gitNames.GitopsRepo)
+	config.DestinationMetaphorRepoURL = fmt.Sprintf("https://%s/%s/%s.git", cGitHost, cGitOwner, gitNames.MetaphorRepo)
+	config.DestinationMetaphorRepoGitURL = fmt.Sprintf("git@%s:%s/%s.git", cGitHost, cGitOwner, gitNames.MetaphorRepo)
 	switch gitProviderFlag {
 	case "github":
 		config.GithubToken = cGitToken
@@ -317,8 +393,6 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		config.GitlabToken = cGitToken
 	}
 
-	var sshPrivateKey, sshPublicKey string
-
 	// todo placed in configmap in kubefirst namespace, included in telemetry
 	clusterId := viper.GetString("kubefirst.cluster-id")
 	if clusterId == "" {
@@ -332,7 +406,7 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	telemetry.SendEvent(segClient, telemetry.ClusterInstallStarted, "")
 
 	// Progress output
-	progressPrinter.AddTracker("preflight-checks", "Running preflight checks", 5)
+	progressPrinter.AddTracker("preflight-checks", "Running preflight checks", 2)
 	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
 	progressPrinter.IncrementTracker("preflight-checks", 1)
 
@@ -389,83 +463,8 @@ func runK3d(cmd *cobra.Command, args []string) error {
 	}
 	progressPrinter.IncrementTracker("preflight-checks", 1)
 
-	// Objects to check for
-	// Repositories that will be created throughout the initialization process
-	newRepositoryNames := []string{"gitops", "metaphor"}
-	newTeamNames := []string{"admins", "developers"}
-
-	// Check git credentials
-	executionControl := viper.GetBool(fmt.Sprintf("kubefirst-checks.%s-credentials", config.GitProvider))
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.GitCredentialsCheckStarted, "")
-		if len(cGitToken) == 0 {
-			msg := fmt.Sprintf(
-				"please set a %s_TOKEN environment variable to continue",
-				strings.ToUpper(config.GitProvider),
-			)
-			telemetry.SendEvent(segClient, telemetry.GitCredentialsCheckFailed, msg)
-			return fmt.Errorf(msg)
-		}
-
-		initGitParameters := gitShim.GitInitParameters{
-			GitProvider:  gitProviderFlag,
-			GitToken:     cGitToken,
-			GitOwner:     cGitOwner,
-			Repositories: newRepositoryNames,
-			Teams:        newTeamNames,
-		}
-		err = gitShim.InitializeGitProvider(&initGitParameters)
-		if err != nil {
-			return err
-		}
-
-		viper.Set(fmt.Sprintf("kubefirst-checks.%s-credentials", config.GitProvider), true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.GitCredentialsCheckCompleted, "")
-		progressPrinter.IncrementTracker("preflight-checks", 1)
-	} else {
-		log.Info().Msg(fmt.Sprintf("already completed %s checks - continuing", config.GitProvider))
-		progressPrinter.IncrementTracker("preflight-checks", 1)
-	}
 	// Swap tokens for git protocol
 	var gitopsRepoURL string
-	executionControl = viper.GetBool("kubefirst-checks.kbot-setup")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.KbotSetupStarted, "")
-
-		log.Info().Msg("creating an ssh key pair for your new cloud infrastructure")
-		sshPrivateKey, sshPublicKey, err = internalssh.CreateSshKeyPair()
-		if err != nil {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.KbotSetupFailed, err.Error())
-			return err
-		}
-		log.Info().Msg("ssh key pair creation complete")
-
-		viper.Set("kbot.private-key", sshPrivateKey)
-		viper.Set("kbot.public-key", sshPublicKey)
-		viper.Set("kbot.username", "kbot")
-		viper.Set("kubefirst-checks.kbot-setup", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.KbotSetupCompleted, "")
-		log.Info().Msg("kbot-setup complete")
-		progressPrinter.IncrementTracker("preflight-checks", 1)
-	} else {
-		log.Info().Msg("already setup kbot user - continuing")
-		progressPrinter.IncrementTracker("preflight-checks", 1)
-	}
-
-	log.Info().Msg("validation and kubefirst cli environment check is complete")
-
-	telemetry.SendEvent(segClient, telemetry.InitCompleted, "")
-	telemetry.SendEvent(segClient, telemetry.InitCompleted, "")
-
-	// Swap tokens for git protocol
 	switch config.GitProtocol {
 	case "https":
 		gitopsRepoURL = config.DestinationGitopsRepoURL
@@ -508,922 +507,84 @@ func runK3d(cmd *cobra.Command, args []string) error {
 		gitopsDirectoryTokens.UseTelemetry = "false"
 	}
 
-	//* generate http credentials for git auth over https
-	httpAuth := &githttps.BasicAuth{
-		Username: cGitUser,
-		Password: cGitToken,
-	}
-
-	if err != nil {
-		log.Info().Msgf("generate public keys failed: %s\n", err.Error())
-	}
-
-	//* download dependencies to `$HOME/.k1/tools`
-	if !viper.GetBool("kubefirst-checks.tools-downloaded") {
-		log.Info().Msg("installing kubefirst dependencies")
-
-		err := k3d.DownloadTools(clusterNameFlag, config.GitProvider, cGitOwner, config.ToolsDir, config.GitProtocol)
-		if err != nil {
-			return err
-		}
-
-		log.Info().Msg("download dependencies `$HOME/.k1/tools` complete")
-		viper.Set("kubefirst-checks.tools-downloaded", true)
-		viper.WriteConfig()
-	} else {
-		log.Info().Msg("already completed download of dependencies to `$HOME/.k1/tools` - continuing")
-	}
-	progressPrinter.IncrementTracker("preflight-checks", 1)
-
 	metaphorTemplateTokens := k3d.MetaphorTokenValues{
 		ClusterName:                   clusterNameFlag,
 		CloudRegion:                   cloudRegionFlag,
-		ContainerRegistryURL:          fmt.Sprintf("%s/%s/metaphor", containerRegistryHost, cGitOwner),
+		ContainerRegistryURL:          fmt.Sprintf("%s/%s/%s", containerRegistryHost, cGitOwner, gitNames.MetaphorRepo),
 		DomainName:                    k3d.DomainName,
 		MetaphorDevelopmentIngressURL: fmt.Sprintf("metaphor-development.%s", k3d.DomainName),
 		MetaphorStagingIngressURL:     fmt.Sprintf("metaphor-staging.%s", k3d.DomainName),
 		MetaphorProductionIngressURL:  fmt.Sprintf("metaphor-production.%s", k3d.DomainName),
 	}
 
-	//* git clone and detokenize the gitops repository
-	// todo improve this logic for removing `kubefirst clean`
-	// if !viper.GetBool("template-repo.gitops.cloned") || viper.GetBool("template-repo.gitops.removed") {
-	progressPrinter.IncrementTracker("preflight-checks", 1)
-	progressPrinter.IncrementTracker("preflight-checks", 1)
-	progressPrinter.AddTracker("cloning-and-formatting-git-repositories", "Cloning and formatting git repositories", 1)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-	if !viper.GetBool("kubefirst-checks.gitops-ready-to-push") {
-		log.Info().Msg("generating your new gitops repository")
-		err := k3d.PrepareGitRepositories(
-			config.GitProvider,
-			clusterNameFlag,
-			clusterTypeFlag,
-			config.DestinationGitopsRepoURL, //default to https for git interactions when creating remotes
-			config.GitopsDir,
-			gitopsTemplateBranchFlag,
-			gitopsTemplateURLFlag,
-			config.DestinationMetaphorRepoURL, //default to https for git interactions when creating remotes
-			config.K1Dir,
-			&gitopsDirectoryTokens,
-			config.MetaphorDir,
-			&metaphorTemplateTokens,
-			gitProtocolFlag,
-		)
-		if err != nil {
-			return err
-		}
-
-		// todo emit init telemetry end
-		viper.Set("kubefirst-checks.gitops-ready-to-push", true)
-		viper.WriteConfig()
-		progressPrinter.IncrementTracker("cloning-and-formatting-git-repositories", 1)
-	} else {
-		log.Info().Msg("already completed gitops repo generation - continuing")
-		progressPrinter.IncrementTracker("cloning-and-formatting-git-repositories", 1)
-	}
-
-	progressPrinter.AddTracker("applying-git-terraform", fmt.Sprintf("Applying %s Terraform", config.GitProvider), 1)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	switch config.GitProvider {
-	case "github":
-		// //* create teams and repositories in github
-		executionControl = viper.GetBool("kubefirst-checks.terraform-apply-github")
-		if !executionControl {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.GitTerraformApplyStarted, "")
-
-			log.Info().Msg("Creating GitHub resources with Terraform")
-
-			tfEntrypoint := config.GitopsDir + "/terraform/github"
-			tfEnvs := map[string]string{}
-			// tfEnvs = k3d.GetGithubTerraformEnvs(tfEnvs)
-			tfEnvs["GITHUB_TOKEN"] = cGitToken
-			tfEnvs["GITHUB_OWNER"] = cGitOwner
-			tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
-			tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
-			tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
-			tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
-			tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
-			// Erase public key to prevent it from being created if the git protocol argument is set to htps
-			switch config.GitProtocol {
-			case "https":
-				tfEnvs["TF_VAR_kbot_ssh_public_key"] = ""
-			}
-			err := terraform.InitApplyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-			if err != nil {
-				segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-				defer segClient.Client.Close()
-				msg := fmt.Sprintf("error creating github resources with terraform %s: %s", tfEntrypoint, err)
-				telemetry.SendEvent(segClient, telemetry.GitTerraformApplyFailed, msg)
-				return fmt.Errorf(msg)
-			}
-
-			log.Info().Msgf("created git repositories for github.com/%s", cGitOwner)
-			viper.Set("kubefirst-checks.terraform-apply-github", true)
-			viper.WriteConfig()
-			telemetry.SendEvent(segClient, telemetry.GitTerraformApplyCompleted, "")
-			progressPrinter.IncrementTracker("applying-git-terraform", 1)
-		} else {
-			log.Info().Msg("already created GitHub Terraform resources")
-			progressPrinter.IncrementTracker("applying-git-terraform", 1)
-		}
-	case "gitlab":
-		// //* create teams and repositories in gitlab
-		executionControl = viper.GetBool("kubefirst-checks.terraform-apply-gitlab")
-		if !executionControl {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.GitTerraformApplyStarted, "")
-
-			log.Info().Msg("Creating GitLab resources with Terraform")
-
-			tfEntrypoint := config.GitopsDir + "/terraform/gitlab"
-			tfEnvs := map[string]string{}
-			tfEnvs["GITLAB_TOKEN"] = cGitToken
-			tfEnvs["GITLAB_OWNER"] = gitlabGroupFlag
-			tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(cGitlabOwnerGroupID)
-			tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
-			tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
-			tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
-			tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
-			tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
-			// Erase public key to prevent it from being created if the git protocol argument is set to htps
-			switch config.GitProtocol {
-			case "https":
-				tfEnvs["TF_VAR_kbot_ssh_public_key"] = ""
-			}
-			err := terraform.InitApplyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-			if err != nil {
-				msg := fmt.Sprintf("error creating gitlab resources with terraform %s: %s", tfEntrypoint, err)
-				segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-				defer segClient.Client.Close()
-				telemetry.SendEvent(segClient, telemetry.GitTerraformApplyFailed, msg)
-				return fmt.Errorf(msg)
-			}
-
-			log.Info().Msgf("created git projects and groups for gitlab.com/%s", gitlabGroupFlag)
-			viper.Set("kubefirst-checks.terraform-apply-gitlab", true)
-			viper.WriteConfig()
-			telemetry.SendEvent(segClient, telemetry.GitTerraformApplyCompleted, "")
-			progressPrinter.IncrementTracker("applying-git-terraform", 1)
-		} else {
-			log.Info().Msg("already created GitLab Terraform resources")
-			progressPrinter.IncrementTracker("applying-git-terraform", 1)
-		}
-	}
-
-	//* push detokenized gitops-template repository content to new remote
-	progressPrinter.AddTracker("pushing-gitops-repos-upstream", "Pushing git repositories", 1)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	log.Info().Msgf("referencing gitops repository: %s", config.DestinationGitopsRepoGitURL)
-	log.Info().Msgf("referencing metaphor repository: %s", config.DestinationMetaphorRepoURL)
-
-	executionControl = viper.GetBool("kubefirst-checks.gitops-repo-pushed")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.GitopsRepoPushStarted, "")
-
-		gitopsRepo, err := git.PlainOpen(config.GitopsDir)
-		if err != nil {
-			log.Info().Msgf("error opening repo at: %s", config.GitopsDir)
-		}
-
-		metaphorRepo, err := git.PlainOpen(config.MetaphorDir)
-		if err != nil {
-			log.Info().Msgf("error opening repo at: %s", config.MetaphorDir)
-		}
-
-		err = internalssh.EvalSSHKey(&internalssh.EvalSSHKeyRequest{
-			GitProvider:     gitProviderFlag,
-			GitlabGroupFlag: gitlabGroupFlag,
-			GitToken:        cGitToken,
-		})
-		if err != nil {
-			return err
-		}
-
-		//Push to remotes and use https
-		// Push gitops repo to remote
-		err = gitopsRepo.Push(
-			&git.PushOptions{
-				RemoteName: config.GitProvider,
-				Auth:       httpAuth,
-			},
-		)
-		if err != nil {
-			msg := fmt.Sprintf("error pushing detokenized gitops repository to remote %s: %s", config.DestinationGitopsRepoGitURL, err)
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.GitopsRepoPushFailed, msg)
-			if !strings.Contains(msg, "already up-to-date") {
-				log.Panic().Msg(msg)
-			}
-		}
-
-		// push metaphor repo to remote
-		err = metaphorRepo.Push(
-			&git.PushOptions{
-				RemoteName: "origin",
-				Auth:       httpAuth,
-			},
-		)
-		if err != nil {
-			msg := fmt.Sprintf("error pushing detokenized metaphor repository to remote %s: %s", config.DestinationMetaphorRepoURL, err)
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.GitopsRepoPushFailed, msg)
-			if !strings.Contains(msg, "already up-to-date") {
-				log.Panic().Msg(msg)
-			}
-		}
-		log.Info().Msgf("successfully pushed gitops and metaphor repositories to https://%s/%s", cGitHost, cGitOwner)
-
-		// todo delete the local gitops repo and re-clone it
-		// todo that way we can stop worrying about which origin we're going to push to
-		viper.Set("kubefirst-checks.gitops-repo-pushed", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.GitopsRepoPushCompleted, "")
-		progressPrinter.IncrementTracker("pushing-gitops-repos-upstream", 1) // todo verify this tracker didnt lose one
-	} else {
-		log.Info().Msg("already pushed detokenized gitops repository content")
-		progressPrinter.IncrementTracker("pushing-gitops-repos-upstream", 1)
-	}
-
-	//* create k3d resources
-
-	progressPrinter.AddTracker("creating-k3d-cluster", "Creating k3d cluster", 1)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	if !viper.GetBool("kubefirst-checks.create-k3d-cluster") {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.CloudTerraformApplyStarted, "")
-
-		log.Info().Msg("Creating k3d cluster")
-
-		err := k3d.ClusterCreate(clusterNameFlag, config.K1Dir, config.K3dClient, config.Kubeconfig)
-		if err != nil {
-			msg := fmt.Sprintf("error creating k3d resources with k3d client %s: %s", config.K3dClient, err)
-			viper.Set("kubefirst-checks.create-k3d-cluster-failed", true)
-			viper.WriteConfig()
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.CloudTerraformApplyFailed, msg)
-			return fmt.Errorf(msg)
-		}
-
-		log.Info().Msg("successfully created k3d cluster")
-		viper.Set("kubefirst-checks.create-k3d-cluster", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.CloudTerraformApplyCompleted, "")
-		progressPrinter.IncrementTracker("creating-k3d-cluster", 1)
-	} else {
-		log.Info().Msg("already created k3d cluster resources")
-		progressPrinter.IncrementTracker("creating-k3d-cluster", 1)
-	}
-
-	kcfg := k8s.CreateKubeConfig(false, config.Kubeconfig)
-
-	// kubernetes.BootstrapSecrets
-	progressPrinter.AddTracker("bootstrapping-kubernetes-resources", "Bootstrapping Kubernetes resources", 2)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	executionControl = viper.GetBool("kubefirst-checks.k8s-secrets-created")
-	if !executionControl {
-		err := k3d.GenerateTLSSecrets(kcfg.Clientset, *config)
-		if err != nil {
-			return err
-		}
-
-		err = k3d.AddK3DSecrets(
-			atlantisWebhookSecret,
-			viper.GetString("kbot.public-key"),
-			gitopsRepoURL,
-			viper.GetString("kbot.private-key"),
-			config.GitProvider,
-			cGitUser,
-			cGitOwner,
-			config.Kubeconfig,
-			cGitToken,
-		)
-		if err != nil {
-			log.Info().Msg("Error adding kubernetes secrets for bootstrap")
-			return err
-		}
-		viper.Set("kubefirst-checks.k8s-secrets-created", true)
-		viper.WriteConfig()
-		progressPrinter.IncrementTracker("bootstrapping-kubernetes-resources", 1)
-	} else {
-		log.Info().Msg("already added secrets to k3d cluster")
-		progressPrinter.IncrementTracker("bootstrapping-kubernetes-resources", 1)
-	}
-
-	// //* check for ssl restore
-	// log.Info().Msg("checking for tls secrets to restore")
-	// secretsFilesToRestore, err := ioutil.ReadDir(config.SSLBackupDir + "/secrets")
-	// if err != nil {
-	// 	log.Info().Msgf("%s", err)
-	// }
-	// if len(secretsFilesToRestore) != 0 {
-	// 	// todo would like these but requires CRD's and is not currently supported
-	// 	// add crds ( use execShellReturnErrors? )
-	// 	// https://raw.githubusercontent.com/cert-manager/cert-manager/v1.11.0/deploy/crds/crd-clusterissuers.yaml
-	// 	// https://raw.githubusercontent.com/cert-manager/cert-manager/v1.11.0/deploy/crds/crd-certificates.yaml
-	// 	// add certificates, and clusterissuers
-	// 	log.Info().Msgf("found %d tls secrets to restore", len(secretsFilesToRestore))
-	// 	ssl.Restore(config.SSLBackupDir, k3d.DomainName, config.Kubeconfig)
-	// } else {
-	// 	log.Info().Msg("no files found in secrets directory, continuing")
-	// }
-
-	// Container registry authentication creation
-	containerRegistryAuth := gitShim.ContainerRegistryAuth{
-		GitProvider:           gitProviderFlag,
-		GitUser:               cGitUser,
-		GitToken:              cGitToken,
-		GitlabGroupFlag:       gitlabGroupFlag,
-		GithubOwner:           cGitOwner,
-		ContainerRegistryHost: containerRegistryHost,
-		Clientset:             kcfg.Clientset,
-	}
-	containerRegistryAuthToken, err := gitShim.CreateContainerRegistrySecret(&containerRegistryAuth)
-	if err != nil {
-		return err
-	}
-	progressPrinter.IncrementTracker("bootstrapping-kubernetes-resources", 1)
-
-	// k3d Readiness checks
-	progressPrinter.AddTracker("verifying-k3d-cluster-readiness", "Verifying Kubernetes cluster is ready", 3)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	// traefik
-	traefikDeployment, err := k8s.ReturnDeploymentObject(
-		kcfg.Clientset,
-		"app.kubernetes.io/name",
-		"traefik",
-		"kube-system",
-		240,
-	)
-	if err != nil {
-		log.Error().Msgf("error finding traefik deployment: %s", err)
-		return err
-	}
-	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, traefikDeployment, 240)
-	if err != nil {
-		log.Error().Msgf("error waiting for traefik deployment ready state: %s", err)
-		return err
-	}
-	progressPrinter.IncrementTracker("verifying-k3d-cluster-readiness", 1)
-
-	// metrics-server
-	metricsServerDeployment, err := k8s.ReturnDeploymentObject(
-		kcfg.Clientset,
-		"k8s-app",
-		"metrics-server",
-		"kube-system",
-		240,
-	)
-	if err != nil {
-		log.Error().Msgf("error finding metrics-server deployment: %s", err)
-		return err
-	}
-	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, metricsServerDeployment, 240)
-	if err != nil {
-		log.Error().Msgf("error waiting for metrics-server deployment ready state: %s", err)
-		return err
-	}
-	progressPrinter.IncrementTracker("verifying-k3d-cluster-readiness", 1)
-
-	time.Sleep(time.Second * 20)
-
-	progressPrinter.IncrementTracker("verifying-k3d-cluster-readiness", 1)
-
-	progressPrinter.AddTracker("installing-argo-cd", "Installing and configuring Argo CD", 3)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	argoCDInstallPath := fmt.Sprintf("github.com:kubefirst/manifests/argocd/k3d?ref=%s", pkg.KubefirstManifestRepoRef)
-
-	//* install argocd
-	executionControl = viper.GetBool("kubefirst-checks.argocd-install")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.ArgoCDInstallStarted, "")
-
-		log.Info().Msgf("installing argocd")
-
-		// Build and apply manifests
-		yamlData, err := kcfg.KustomizeBuild(argoCDInstallPath)
-		if err != nil {
-			return err
-		}
-		output, err := kcfg.SplitYAMLFile(yamlData)
-		if err != nil {
-			return err
-		}
-		err = kcfg.ApplyObjects("", output)
-		if err != nil {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.ArgoCDInstallFailed, err.Error())
-			return err
-		}
-
-		viper.Set("kubefirst-checks.argocd-install", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.ArgoCDInstallCompleted, "")
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	} else {
-		log.Info().Msg("argo cd already installed, continuing")
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	}
-
-	// Wait for ArgoCD to be ready
-	_, err = k8s.VerifyArgoCDReadiness(kcfg.Clientset, true, 300)
-	if err != nil {
-		log.Error().Msgf("error waiting for ArgoCD to become ready: %s", err)
-		return err
-	}
-
-	var argocdPassword string
-	//* argocd pods are ready, get and set credentials
-	executionControl = viper.GetBool("kubefirst-checks.argocd-credentials-set")
-	if !executionControl {
-		log.Info().Msg("Setting argocd username and password credentials")
-
-		argocd.ArgocdSecretClient = kcfg.Clientset.CoreV1().Secrets("argocd")
-
-		argocdPassword = k8s.GetSecretValue(argocd.ArgocdSecretClient, "argocd-initial-admin-secret", "password")
-		if argocdPassword == "" {
-			log.Info().Msg("argocd password not found in secret")
-			return err
-		}
-
-		viper.Set("components.argocd.password", argocdPassword)
-		viper.Set("components.argocd.username", "admin")
-		viper.WriteConfig()
-		log.Info().Msg("argocd username and password credentials set successfully")
-		log.Info().Msg("Getting an argocd auth token")
-
-		// Test https to argocd
-		var argoCDToken string
-		// only the host, not the protocol
-		err := helpers.TestEndpointTLS(strings.Replace(k3d.ArgocdURL, "https://", "", 1))
-		if err != nil {
-			argoCDStopChannel := make(chan struct{}, 1)
-			log.Info().Msgf("argocd not available via https, using http")
-			defer func() {
-				close(argoCDStopChannel)
-			}()
-			k8s.OpenPortForwardPodWrapper(
-				kcfg.Clientset,
-				kcfg.RestConfig,
-				"argocd-server",
-				"argocd",
-				8080,
-				8080,
-				argoCDStopChannel,
-			)
-			argoCDHTTPURL := strings.Replace(
-				k3d.ArgocdURL,
-				"https://",
-				"http://",
-				1,
-			) + ":8080"
-			argoCDToken, err = argocd.GetArgocdTokenV2(httpClient, argoCDHTTPURL, "admin", argocdPassword)
-			if err != nil {
-				return err
-			}
-		} else {
-			argoCDToken, err = argocd.GetArgocdTokenV2(httpClient, k3d.ArgocdURL, "admin", argocdPassword)
-			if err != nil {
-				return err
-			}
-		}
-
-		log.Info().Msg("argocd admin auth token set")
-
-		viper.Set("components.argocd.auth-token", argoCDToken)
-		viper.Set("kubefirst-checks.argocd-credentials-set", true)
-		viper.WriteConfig()
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	} else {
-		log.Info().Msg("argo credentials already set, continuing")
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	}
-
-	if configs.K1Version == "development" {
-		err := clipboard.WriteAll(argocdPassword)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-		}
-
-		err = pkg.OpenBrowser(pkg.ArgoCDLocalURLTLS)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-		}
-	}
-
-	//* argocd sync registry and start sync waves
-	executionControl = viper.GetBool("kubefirst-checks.argocd-create-registry")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.CreateRegistryStarted, "")
-		argocdClient, err := argocdapi.NewForConfig(kcfg.RestConfig)
-		if err != nil {
-			return err
-		}
-
-		log.Info().Msg("applying the registry application to argocd")
-		registryApplicationObject := argocd.GetArgoCDApplicationObject(gitopsRepoURL, fmt.Sprintf("registry/%s", clusterNameFlag))
-
-		_, _ = argocdClient.ArgoprojV1alpha1().Applications("argocd").Create(context.Background(), registryApplicationObject, metav1.CreateOptions{})
-		viper.Set("kubefirst-checks.argocd-create-registry", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.CreateRegistryCompleted, "")
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	} else {
-		log.Info().Msg("argocd registry create already done, continuing")
-		progressPrinter.IncrementTracker("installing-argo-cd", 1)
-	}
-
-	// Wait for Vault StatefulSet Pods to transition to Running
-	progressPrinter.AddTracker("configuring-vault", "Configuring Vault", 4)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	vaultStatefulSet, err := k8s.ReturnStatefulSetObject(
-		kcfg.Clientset,
-		"app.kubernetes.io/instance",
-		"vault",
-		"vault",
-		120,
-	)
-	if err != nil {
-		log.Error().Msgf("Error finding Vault StatefulSet: %s", err)
-		return err
-	}
-	_, err = k8s.WaitForStatefulSetReady(kcfg.Clientset, vaultStatefulSet, 120, true)
-	if err != nil {
-		log.Error().Msgf("Error waiting for Vault StatefulSet ready state: %s", err)
-		return err
-	}
-	progressPrinter.IncrementTracker("configuring-vault", 1)
-
-	// Init and unseal vault
-	// We need to wait before we try to run any of these commands or there may be
-	// unexpected timeouts
-	time.Sleep(time.Second * 10)
-	progressPrinter.IncrementTracker("configuring-vault", 1)
-
-	executionControl = viper.GetBool("kubefirst-checks.vault-initialized")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.VaultInitializationStarted, "")
-
-		// Initialize and unseal Vault
-		vaultHandlerPath := "github.com:kubefirst/manifests.git/vault-handler/replicas-1"
-
-		// Build and apply manifests
-		yamlData, err := kcfg.KustomizeBuild(vaultHandlerPath)
-		if err != nil {
-			return err
-		}
-		output, err := kcfg.SplitYAMLFile(yamlData)
-		if err != nil {
-			return err
-		}
-		err = kcfg.ApplyObjects("", output)
-		if err != nil {
-			return err
-		}
-
-		// Wait for the Job to finish
-		job, err := k8s.ReturnJobObject(kcfg.Clientset, "vault", "vault-handler")
-		if err != nil {
-			return err
+	install := &k3dInstall{
+		clusterName:            clusterNameFlag,
+		clusterType:            clusterTypeFlag,
+		gitProvider:            gitProviderFlag,
+		gitProtocol:            gitProtocolFlag,
+		gitNames:               gitNames,
+		gitlabGroup:            gitlabGroupFlag,
+		gitopsTemplateURL:      gitopsTemplateURLFlag,
+		gitopsTemplateBranch:   gitopsTemplateBranchFlag,
+		useTelemetry:           useTelemetryFlag,
+		config:                 config,
+		httpClient:             httpClient,
+		gitHost:                cGitHost,
+		gitOwner:               cGitOwner,
+		gitUser:                cGitUser,
+		gitToken:               cGitToken,
+		gitlabOwnerGroupID:     cGitlabOwnerGroupID,
+		containerRegistryHost:  containerRegistryHost,
+		atlantisWebhookSecret:  atlantisWebhookSecret,
+		gitopsRepoURL:          gitopsRepoURL,
+		gitopsDirectoryTokens:  &gitopsDirectoryTokens,
+		metaphorTemplateTokens: &metaphorTemplateTokens,
+		bundle:                 toolsBundle,
+		//* generate http credentials for git auth over https
+		httpAuth: &githttps.BasicAuth{
+			Username: cGitUser,
+			Password: cGitToken,
+		},
+	}
+	defer install.closePortForwards()
+
+	graph := newK3dInstallGraph(install, segClient)
+	if eventsFileFlag != "" {
+		graph.Observe(progress.StepEvents{ClusterName: clusterNameFlag})
+	}
+
+	err = graph.Run(ctx)
+	if err != nil {
+		if rollbackOnFailureFlag {
+			return rollbackK3d(ctx, graph, err)
 		}
-		_, err = k8s.WaitForJobComplete(kcfg.Clientset, job, 240)
-		if err != nil {
-			msg := fmt.Sprintf("could not run vault unseal job: %s", err)
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.VaultInitializationFailed, msg)
-			log.Fatal().Msg(msg)
-		}
-
-		viper.Set("kubefirst-checks.vault-initialized", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.VaultInitializationCompleted, "")
-		progressPrinter.IncrementTracker("configuring-vault", 1)
-	} else {
-		log.Info().Msg("vault is already initialized - skipping")
-		progressPrinter.IncrementTracker("configuring-vault", 1)
-	}
-
-	minioStopChannel := make(chan struct{}, 1)
-	defer func() {
-		close(minioStopChannel)
-	}()
-	k8s.OpenPortForwardPodWrapper(
-		kcfg.Clientset,
-		kcfg.RestConfig,
-		"minio",
-		"minio",
-		9000,
-		9000,
-		minioStopChannel,
-	)
-
-	// Initialize minio client object.
-	minioClient, err := minio.New(pkg.MinioPortForwardEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(pkg.MinioDefaultUsername, pkg.MinioDefaultPassword, ""),
-		Secure: false,
-		Region: pkg.MinioRegion,
-	})
-	if err != nil {
-		log.Info().Msgf("Error creating Minio client: %s", err)
-	}
-
-	//define upload object
-	objectName := fmt.Sprintf("terraform/%s/terraform.tfstate", config.GitProvider)
-	filePath := config.K1Dir + fmt.Sprintf("/gitops/%s", objectName)
-	contentType := "xl.meta"
-	bucketName := "kubefirst-state-store"
-	log.Info().Msgf("BucketName: %s", bucketName)
-
-	viper.Set("kubefirst.state-store.name", bucketName)
-	viper.Set("kubefirst.state-store.hostname", "minio-console.kubefirst.dev")
-	viper.Set("kubefirst.state-store-creds.access-key-id", pkg.MinioDefaultUsername)
-	viper.Set("kubefirst.state-store-creds.secret-access-key-id", pkg.MinioDefaultPassword)
-
-	// Upload the zip file with FPutObject
-	info, err := minioClient.FPutObject(ctx, bucketName, objectName, filePath, minio.PutObjectOptions{ContentType: contentType})
-	if err != nil {
-		log.Info().Msgf("Error uploading to Minio bucket: %s", err)
-	}
-
-	log.Printf("Successfully uploaded %s to bucket %s\n", objectName, info.Bucket)
-
-	progressPrinter.IncrementTracker("configuring-vault", 1)
-
-	//* configure vault with terraform
-	//* vault port-forward
-	vaultStopChannel := make(chan struct{}, 1)
-	defer func() {
-		close(vaultStopChannel)
-	}()
-	k8s.OpenPortForwardPodWrapper(
-		kcfg.Clientset,
-		kcfg.RestConfig,
-		"vault-0",
-		"vault",
-		8200,
-		8200,
-		vaultStopChannel,
-	)
-
-	// Retrieve root token from init step
-	var vaultRootToken string
-	secData, err := k8s.ReadSecretV2(kcfg.Clientset, "vault", "vault-unseal-secret")
-	if err != nil {
 		return err
 	}
 
-	vaultRootToken = secData["root-token"]
+	progress.StepEvents{ClusterName: clusterNameFlag}.Provisioned()
 
-	// Parse k3d api endpoint from kubeconfig
-	// In this case, we need to get the IP of the in-cluster API server to provide to Vault
-	// to work with Kubernetes auth
-	kubernetesInClusterAPIService, err := k8s.ReadService(config.Kubeconfig, "default", "kubernetes")
-	if err != nil {
-		log.Error().Msgf("error looking up kubernetes api server service: %s", err)
-		return err
-	}
+	log.Info().Msg("kubefirst installation complete")
+	log.Info().Msg("welcome to your new kubefirst platform running in K3d")
+	time.Sleep(time.Second * 1) // allows progress bars to finish
 
-	err = helpers.TestEndpointTLS(strings.Replace(k3d.VaultURL, "https://", "", 1))
-	if err != nil {
-		return fmt.Errorf(
-			"unable to reach vault over https - this is likely due to the mkcert certificate store missing. please install it via `%s -install`", config.MkCertClient,
-		)
+	if !ciFlag {
+		reports.LocalHandoffScreenV2(viper.GetString("components.argocd.password"), clusterNameFlag, gitDestDescriptor, cGitOwner, config, false)
 	}
 
-	//* configure vault with terraform
-	executionControl = viper.GetBool("kubefirst-checks.terraform-apply-vault")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.VaultTerraformApplyStarted, "")
-
-		tfEnvs := map[string]string{}
-		var usernamePasswordString, base64DockerAuth string
-
-		if config.GitProvider == "gitlab" {
-			usernamePasswordString = fmt.Sprintf("%s:%s", "container-registry-auth", containerRegistryAuthToken)
-			base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
-
-			tfEnvs["TF_VAR_container_registry_auth"] = containerRegistryAuthToken
-			tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(cGitlabOwnerGroupID)
-		} else {
-			usernamePasswordString = fmt.Sprintf("%s:%s", cGitUser, cGitToken)
-			base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
-
-		}
-
-		log.Info().Msg("configuring vault with terraform")
-
-		tfEnvs["TF_VAR_email_address"] = "your@email.com"
-		tfEnvs[fmt.Sprintf("TF_VAR_%s_token", config.GitProvider)] = cGitToken
-		tfEnvs[fmt.Sprintf("TF_VAR_%s_user", config.GitProvider)] = cGitUser
-		tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
-		tfEnvs["TF_VAR_b64_docker_auth"] = base64DockerAuth
-		tfEnvs["TF_VAR_vault_token"] = vaultRootToken
-		tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
-		tfEnvs["VAULT_TOKEN"] = vaultRootToken
-		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-		tfEnvs["TF_VAR_kbot_ssh_private_key"] = viper.GetString("kbot.private-key")
-		tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
-		tfEnvs["TF_VAR_kubernetes_api_endpoint"] = fmt.Sprintf("https://%s", kubernetesInClusterAPIService.Spec.ClusterIP)
-		tfEnvs[fmt.Sprintf("%s_OWNER", strings.ToUpper(config.GitProvider))] = viper.GetString(fmt.Sprintf("flags.%s-owner", config.GitProvider))
-		tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
-		tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
-		tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
-		tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
-		// tfEnvs["TF_LOG"] = "DEBUG"
-
-		tfEntrypoint := config.GitopsDir + "/terraform/vault"
-		err := terraform.InitApplyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-		if err != nil {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.VaultTerraformApplyStarted, err.Error())
-			return err
-		}
-
-		log.Info().Msg("vault terraform executed successfully")
-		viper.Set("kubefirst-checks.terraform-apply-vault", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.VaultTerraformApplyCompleted, "")
-		progressPrinter.IncrementTracker("configuring-vault", 1)
-	} else {
-		log.Info().Msg("already executed vault terraform")
-		progressPrinter.IncrementTracker("configuring-vault", 1)
-	}
-
-	//* create users
-	progressPrinter.AddTracker("creating-users", "Creating users", 1)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	executionControl = viper.GetBool("kubefirst-checks.terraform-apply-users")
-	if !executionControl {
-		segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-		defer segClient.Client.Close()
-		telemetry.SendEvent(segClient, telemetry.UsersTerraformApplyStarted, "")
-
-		log.Info().Msg("applying users terraform")
-
-		tfEnvs := map[string]string{}
-		tfEnvs["TF_VAR_email_address"] = "your@email.com"
-		tfEnvs[fmt.Sprintf("TF_VAR_%s_token", config.GitProvider)] = cGitToken
-		tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
-		tfEnvs["TF_VAR_vault_token"] = vaultRootToken
-		tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
-		tfEnvs["VAULT_TOKEN"] = vaultRootToken
-		tfEnvs[fmt.Sprintf("%s_TOKEN", strings.ToUpper(config.GitProvider))] = cGitToken
-		tfEnvs[fmt.Sprintf("%s_OWNER", strings.ToUpper(config.GitProvider))] = cGitOwner
-
-		tfEntrypoint := config.GitopsDir + "/terraform/users"
-		err := terraform.InitApplyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-		if err != nil {
-			segClient := segment.InitClient(clusterId, clusterTypeFlag, gitProviderFlag)
-			defer segClient.Client.Close()
-			telemetry.SendEvent(segClient, telemetry.UsersTerraformApplyStarted, err.Error())
-			return err
-		}
-		log.Info().Msg("executed users terraform successfully")
-		// progressPrinter.IncrementTracker("step-users", 1)
-		viper.Set("kubefirst-checks.terraform-apply-users", true)
-		viper.WriteConfig()
-		telemetry.SendEvent(segClient, telemetry.UsersTerraformApplyCompleted, "")
-		progressPrinter.IncrementTracker("creating-users", 1)
-	} else {
-		log.Info().Msg("already created users with terraform")
-		progressPrinter.IncrementTracker("creating-users", 1)
-	}
-
-	//PostRun string replacement
-	progressPrinter.AddTracker("wrapping-up", "Wrapping up", 2)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
-	err = k3d.PostRunPrepareGitopsRepository(clusterNameFlag,
-		config.GitopsDir,
-		&gitopsDirectoryTokens,
-	)
-	if err != nil {
-		log.Info().Msgf("Error detokenize post run: %s", err)
-	}
-	gitopsRepo, err := git.PlainOpen(config.GitopsDir)
-	if err != nil {
-		log.Info().Msgf("error opening repo at: %s", config.GitopsDir)
-	}
-	//check if file exists before rename
-	_, err = os.Stat(fmt.Sprintf("%s/terraform/%s/remote-backend.md", config.GitopsDir, config.GitProvider))
-	if err == nil {
-		err = os.Rename(fmt.Sprintf("%s/terraform/%s/remote-backend.md", config.GitopsDir, config.GitProvider), fmt.Sprintf("%s/terraform/%s/remote-backend.tf", config.GitopsDir, config.GitProvider))
-		if err != nil {
-			return err
-		}
-	}
-	viper.Set("kubefirst-checks.post-detokenize", true)
-	viper.WriteConfig()
-
-	// Final gitops repo commit and push
-	err = gitClient.Commit(gitopsRepo, "committing initial detokenized gitops-template repo content post run")
-	if err != nil {
-		return err
-	}
-	err = gitopsRepo.Push(&git.PushOptions{
-		RemoteName: config.GitProvider,
-		Auth:       httpAuth,
-	})
-	if err != nil {
-		log.Info().Msgf("Error pushing repo: %s", err)
-	}
-
-	progressPrinter.IncrementTracker("wrapping-up", 1)
+	return nil
+}
 
-	// Wait for console Deployment Pods to transition to Running
-	argoDeployment, err := k8s.ReturnDeploymentObject(
-		kcfg.Clientset,
-		"app.kubernetes.io/instance",
-		"argo",
-		"argo",
-		1200,
-	)
-	if err != nil {
-		log.Error().Msgf("Error finding argo workflows Deployment: %s", err)
-		return err
-	}
-	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, argoDeployment, 120)
-	if err != nil {
-		log.Error().Msgf("Error waiting for argo workflows Deployment ready state: %s", err)
-		return err
+// resolveGitProvider returns the git provider of an earlier k3d install when --git-provider
+// is not set, so that resuming, planning and clearing steps use the checks that install wrote
+func resolveGitProvider(cmd *cobra.Command, gitProviderFlag string) string {
+	if cmd.Flags().Changed("git-provider") || viper.GetString("kubefirst.cloud-provider") != "k3d" {
+		return gitProviderFlag
 	}
 
-	// Set flags used to track status of active options
-	helpers.SetClusterStatusFlags(k3d.CloudProvider, config.GitProvider)
-
-	cluster := utilities.CreateClusterRecordFromRaw(useTelemetryFlag, cGitOwner, cGitUser, cGitToken, cGitlabOwnerGroupID, gitopsTemplateURLFlag, gitopsTemplateBranchFlag)
-
-	err = utilities.ExportCluster(cluster, kcfg)
-	if err != nil {
-		log.Error().Err(err).Msg("error exporting cluster object")
-		viper.Set("kubefirst.setup-complete", false)
-		viper.Set("kubefirst-checks.cluster-install-complete", false)
-		viper.WriteConfig()
-		return err
-	} else {
-		kubefirstDeployment, err := k8s.ReturnDeploymentObject(
-			kcfg.Clientset,
-			"app.kubernetes.io/instance",
-			"kubefirst",
-			"kubefirst",
-			600,
-		)
-		if err != nil {
-			log.Error().Msgf("Error finding kubefirst Deployment: %s", err)
-			return err
-		}
-		_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, kubefirstDeployment, 120)
-		if err != nil {
-			log.Error().Msgf("Error waiting for kubefirst Deployment ready state: %s", err)
-			return err
-		}
-		progressPrinter.IncrementTracker("wrapping-up", 1)
-
-		err = pkg.OpenBrowser(pkg.KubefirstConsoleLocalURLTLS)
-		if err != nil {
-			log.Error().Err(err).Msg("")
-		}
-
-		// Mark cluster install as complete
-		telemetry.SendEvent(segClient, telemetry.ClusterInstallCompleted, "")
-		viper.Set("kubefirst-checks.cluster-install-complete", true)
-		viper.WriteConfig()
-
-		log.Info().Msg("kubefirst installation complete")
-		log.Info().Msg("welcome to your new kubefirst platform running in K3d")
-		time.Sleep(time.Second * 1) // allows progress bars to finish
-
-		if !ciFlag {
-			reports.LocalHandoffScreenV2(viper.GetString("components.argocd.password"), clusterNameFlag, gitDestDescriptor, cGitOwner, config, false)
-		}
+	gitProvider := viper.GetString("flags.git-provider")
+	if slices.Contains(supportedGitProviders, gitProvider) {
+		return gitProvider
 	}
 
-	return nil
+	return gitProviderFlag
 }
@@ -7,6 +7,7 @@ See the LICENSE file for more details.
 package k3d
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -14,12 +15,13 @@ import (
 	"time"
 
 	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/steps"
+	"github.com/kubefirst/kubefirst/internal/utilities"
 	"github.com/kubefirst/runtime/pkg"
 	gitlab "github.com/kubefirst/runtime/pkg/gitlab"
 	"github.com/kubefirst/runtime/pkg/helpers"
 	"github.com/kubefirst/runtime/pkg/k3d"
 	"github.com/kubefirst/runtime/pkg/k8s"
-	"github.com/kubefirst/runtime/pkg/progressPrinter"
 	"github.com/kubefirst/runtime/pkg/terraform"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -46,14 +48,8 @@ func destroyK3d(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s (maybe the handoff screen is still open in another terminal) - this port is required to tear down your kubefirst environment - please close any existing port forwards before continuing", err.Error())
 	}
 
-	progressPrinter.AddTracker("preflight-checks", "Running preflight checks", 1)
-	progressPrinter.AddTracker("platform-destroy", "Destroying your kubefirst platform", 2)
-	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
-
 	log.Info().Msg("destroying kubefirst platform running in k3d")
 
-	atlantisWebhookURL := fmt.Sprintf("%s/events", viper.GetString("ngrok.host"))
-
 	// Switch based on git provider, set params
 	var cGitOwner, cGitToken string
 	switch gitProvider {
@@ -76,10 +72,6 @@ func destroyK3d(cmd *cobra.Command, args []string) error {
 		config.GitlabToken = cGitToken
 	}
 
-	log.Info().Msg("destroying kubefirst platform running in k3d")
-
-	kcfg := k8s.CreateKubeConfig(false, config.Kubeconfig)
-
 	// todo improve these checks, make them standard for
 	// both create and destroy
 	if len(cGitToken) == 0 {
@@ -89,172 +81,265 @@ func destroyK3d(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	if viper.GetBool("kubefirst-checks.post-detokenize") {
-		// Temporary func to allow destroy
-		err = k3d.ResolveMinioLocal(fmt.Sprintf("%s/terraform", config.GitopsDir))
-		if err != nil {
-			log.Fatal().Msgf("error preloading files for terraform destroy: %s", err)
-		}
-		minioStopChannel := make(chan struct{}, 1)
-		defer func() {
-			close(minioStopChannel)
-		}()
-		k8s.OpenPortForwardPodWrapper(
-			kcfg.Clientset,
-			kcfg.RestConfig,
-			"minio",
-			"minio",
-			9000,
-			9000,
-			minioStopChannel,
-		)
+	install := &k3dInstall{
+		clusterName: clusterName,
+		gitProvider: gitProvider,
+		gitProtocol: gitProtocol,
+		config:      config,
+		gitOwner:    cGitOwner,
+		gitToken:    cGitToken,
+		gitNames:    utilities.GitNamesFromConfig(),
+	}
+	defer install.closePortForwards()
+
+	err = newK3dDestroyGraph(install).Run(context.Background())
+	if err != nil {
+		return err
 	}
 
-	progressPrinter.IncrementTracker("preflight-checks", 1)
+	time.Sleep(time.Millisecond * 200) // allows progress bars to finish
+	fmt.Printf("Your kubefirst platform running in %s has been destroyed.", k3d.CloudProvider)
+	progress.Progress.Quit()
 
-	switch gitProvider {
+	return nil
+}
+
+// newK3dDestroyGraph returns the steps tearing down a k3d install, each one runs only
+// when what it removes was created and clears the matching kubefirst check
+func newK3dDestroyGraph(install *k3dInstall) *steps.Graph {
+	graph := steps.NewTeardownGraph(nil)
+
+	err := graph.Add(
+		steps.Step{
+			Name:        "state-store-connected",
+			Description: "Running preflight checks",
+			Condition: func() bool {
+				return viper.GetBool("kubefirst-checks.post-detokenize")
+			},
+			Run: install.connectStateStore,
+		},
+		steps.Step{
+			Name:        "terraform-destroy-git",
+			Description: fmt.Sprintf("Destroying %s Terraform", install.gitProvider),
+			DependsOn:   []string{"state-store-connected"},
+			Check:       fmt.Sprintf("terraform-apply-%s", install.gitProvider),
+			Run:         install.destroyGitTerraform,
+		},
+		steps.Step{
+			Name:        "delete-k3d-cluster",
+			Description: "Deleting k3d cluster",
+			DependsOn:   []string{"terraform-destroy-git"},
+			Check:       "create-k3d-cluster",
+			Condition: func() bool {
+				return viper.GetBool("kubefirst-checks.create-k3d-cluster") || viper.GetBool("kubefirst-checks.create-k3d-cluster-failed")
+			},
+			Run: install.deleteCluster,
+		},
+		steps.Step{
+			Name:        "delete-gitlab-ssh-key",
+			Description: "Deleting managed ssh key",
+			DependsOn:   []string{"terraform-destroy-git"},
+			Condition: func() bool {
+				return viper.GetString("kbot.gitlab-user-based-ssh-key-title") != ""
+			},
+			Run: install.deleteGitlabSSHKey,
+		},
+		steps.Step{
+			Name:        "reset-local-content",
+			Description: "Removing local content",
+			DependsOn:   []string{"delete-k3d-cluster", "delete-gitlab-ssh-key"},
+			Condition: func() bool {
+				return !viper.GetBool(fmt.Sprintf("kubefirst-checks.terraform-apply-%s", install.gitProvider)) &&
+					!viper.GetBool("kubefirst-checks.create-k3d-cluster")
+			},
+			Run: install.resetLocalContent,
+		},
+		steps.Step{
+			Name:        "remove-kubeconfig",
+			Description: "Removing kubeconfig",
+			DependsOn:   []string{"reset-local-content"},
+			Run:         install.removeKubeconfig,
+		},
+	)
+	if err != nil {
+		// the steps above are static, an error here is a programming mistake
+		log.Panic().Msgf("invalid k3d destroy graph: %s", err)
+	}
+
+	return graph
+}
+
+// connectStateStore makes the terraform state in minio reachable for terraform destroy
+func (i *k3dInstall) connectStateStore(ctx context.Context) error {
+	// Temporary func to allow destroy
+	err := k3d.ResolveMinioLocal(fmt.Sprintf("%s/terraform", i.config.GitopsDir))
+	if err != nil {
+		return fmt.Errorf("error preloading files for terraform destroy: %s", err)
+	}
+
+	i.openPortForward("minio", "minio", 9000)
+
+	return nil
+}
+
+// destroyGitTerraform removes the teams and repositories created by the git terraform
+func (i *k3dInstall) destroyGitTerraform(ctx context.Context) error {
+	atlantisWebhookURL := fmt.Sprintf("%s/events", viper.GetString("ngrok.host"))
+
+	tfEntrypoint := fmt.Sprintf("%s/terraform/%s", i.config.GitopsDir, i.config.GitProvider)
+	tfEnvs := map[string]string{}
+
+	switch i.config.GitProvider {
 	case "github":
-		if viper.GetBool("kubefirst-checks.terraform-apply-github") {
-			log.Info().Msg("destroying github resources with terraform")
-
-			tfEntrypoint := config.GitopsDir + "/terraform/github"
-			tfEnvs := map[string]string{}
-
-			tfEnvs["GITHUB_TOKEN"] = cGitToken
-			tfEnvs["GITHUB_OWNER"] = cGitOwner
-			tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-			tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-			tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
-			tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
-			tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
-			tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
-			tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
-
-			err := terraform.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-			if err != nil {
-				log.Printf("error executing terraform destroy %s", tfEntrypoint)
-				return err
-			}
-			viper.Set("kubefirst-checks.terraform-apply-github", false)
-			viper.WriteConfig()
-			log.Info().Msg("github resources terraform destroyed")
-			progressPrinter.IncrementTracker("platform-destroy", 1)
-		}
+		log.Info().Msg("destroying github resources with terraform")
+
+		tfEnvs["GITHUB_TOKEN"] = i.gitToken
+		tfEnvs["GITHUB_OWNER"] = i.gitOwner
+		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+		tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
+		tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
+		tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
+		tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
+		tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
+		tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
 	case "gitlab":
-		if viper.GetBool("kubefirst-checks.terraform-apply-gitlab") {
-			log.Info().Msg("destroying gitlab resources with terraform")
-			gitlabClient, err := gitlab.NewGitLabClient(cGitToken, cGitOwner)
+		log.Info().Msg("destroying gitlab resources with terraform")
+		gitlabClient, err := gitlab.NewGitLabClient(i.gitToken, i.gitOwner)
+		if err != nil {
+			return err
+		}
+
+		// Before removing Terraform resources, remove any container registry repositories
+		// since failing to remove them beforehand will result in an apply failure
+		for _, project := range i.gitNames.Repositories() {
+			projectExists, err := gitlabClient.CheckProjectExists(project)
 			if err != nil {
-				return err
+				return fmt.Errorf("could not check for existence of project %s: %s", project, err)
+			}
+			if !projectExists {
+				log.Info().Msgf("project %s does not exist, skipping", project)
+				continue
 			}
 
-			// Before removing Terraform resources, remove any container registry repositories
-			// since failing to remove them beforehand will result in an apply failure
-			var projectsForDeletion = []string{"gitops", "metaphor"}
-			for _, project := range projectsForDeletion {
-				projectExists, err := gitlabClient.CheckProjectExists(project)
+			log.Info().Msgf("checking project %s for container registries...", project)
+			crr, err := gitlabClient.GetProjectContainerRegistryRepositories(project)
+			if err != nil {
+				return fmt.Errorf("could not retrieve container registry repositories: %s", err)
+			}
+			if len(crr) == 0 {
+				log.Info().Msgf("project %s does not have any container registries, skipping", project)
+				continue
+			}
+			for _, cr := range crr {
+				err := gitlabClient.DeleteContainerRegistryRepository(project, cr.ID)
 				if err != nil {
-					log.Fatal().Msgf("could not check for existence of project %s: %s", project, err)
-				}
-				if projectExists {
-					log.Info().Msgf("checking project %s for container registries...", project)
-					crr, err := gitlabClient.GetProjectContainerRegistryRepositories(project)
-					if err != nil {
-						log.Fatal().Msgf("could not retrieve container registry repositories: %s", err)
-					}
-					if len(crr) > 0 {
-						for _, cr := range crr {
-							err := gitlabClient.DeleteContainerRegistryRepository(project, cr.ID)
-							if err != nil {
-								log.Fatal().Msgf("error deleting container registry repository: %s", err)
-							}
-						}
-					} else {
-						log.Info().Msgf("project %s does not have any container registries, skipping", project)
-					}
-				} else {
-					log.Info().Msgf("project %s does not exist, skipping", project)
+					return fmt.Errorf("error deleting container registry repository: %s", err)
 				}
 			}
+		}
 
-			tfEntrypoint := config.GitopsDir + "/terraform/gitlab"
-			tfEnvs := map[string]string{}
-
-			tfEnvs["GITLAB_TOKEN"] = cGitToken
-			tfEnvs["GITLAB_OWNER"] = cGitOwner
-			tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
-			tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
-			tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(gitlabClient.ParentGroupID)
+		tfEnvs["GITLAB_TOKEN"] = i.gitToken
+		tfEnvs["GITLAB_OWNER"] = i.gitOwner
+		tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+		tfEnvs["TF_VAR_atlantis_repo_webhook_url"] = atlantisWebhookURL
+		tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(gitlabClient.ParentGroupID)
+	}
+	for key, value := range i.gitNames.TerraformEnvs() {
+		tfEnvs[key] = value
+	}
 
-			err = terraform.InitDestroyAutoApprove(config.TerraformClient, tfEntrypoint, tfEnvs)
-			if err != nil {
-				log.Printf("error executing terraform destroy %s", tfEntrypoint)
-				return err
-			}
-			viper.Set("kubefirst-checks.terraform-apply-gitlab", false)
-			viper.WriteConfig()
-			log.Info().Msg("gitlab resources terraform destroyed")
-			progressPrinter.IncrementTracker("platform-destroy", 1)
-		}
+	err := terraform.InitDestroyAutoApprove(i.config.TerraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		log.Printf("error executing terraform destroy %s", tfEntrypoint)
+		return err
 	}
+	log.Info().Msgf("%s resources terraform destroyed", i.config.GitProvider)
 
-	if viper.GetBool("kubefirst-checks.create-k3d-cluster") || viper.GetBool("kubefirst-checks.create-k3d-cluster-failed") {
-		log.Info().Msg("destroying k3d resources with terraform")
+	return nil
+}
 
-		err := k3d.DeleteK3dCluster(clusterName, config.K1Dir, config.K3dClient)
+// rollbackGitTerraform destroys the git terraform of a failed install, reading its
+// state from the cluster once post-detokenize moved it there
+func (i *k3dInstall) rollbackGitTerraform(ctx context.Context) error {
+	if viper.GetBool("kubefirst-checks.post-detokenize") {
+		err := i.connectStateStore(ctx)
 		if err != nil {
 			return err
 		}
+	}
 
-		viper.Set("kubefirst-checks.create-k3d-cluster", false)
-		viper.WriteConfig()
-		log.Info().Msg("k3d resources terraform destroyed")
-		progressPrinter.IncrementTracker("platform-destroy", 1)
+	err := i.destroyGitTerraform(ctx)
+	if err != nil {
+		return err
 	}
 
-	// remove ssh key provided one was created
 	if viper.GetString("kbot.gitlab-user-based-ssh-key-title") != "" {
-		gitlabClient, err := gitlab.NewGitLabClient(cGitToken, cGitOwner)
-		if err != nil {
-			return err
-		}
-		log.Info().Msg("attempting to delete managed ssh key...")
-		err = gitlabClient.DeleteUserSSHKey(viper.GetString("kbot.gitlab-user-based-ssh-key-title"))
-		if err != nil {
-			log.Warn().Msg(err.Error())
-		}
+		return i.deleteGitlabSSHKey(ctx)
 	}
 
-	//* remove local content and kubefirst config file for re-execution
-	if !viper.GetBool(fmt.Sprintf("kubefirst-checks.terraform-apply-%s", gitProvider)) && !viper.GetBool("kubefirst-checks.create-k3d-cluster") {
-		log.Info().Msg("removing previous platform content")
+	return nil
+}
 
-		err := pkg.ResetK1Dir(config.K1Dir)
-		if err != nil {
-			return err
-		}
-		log.Info().Msg("previous platform content removed")
-
-		log.Info().Msg("resetting `$HOME/.kubefirst` config")
-		viper.Set("argocd", "")
-		viper.Set(gitProvider, "")
-		viper.Set("components", "")
-		viper.Set("kbot", "")
-		viper.Set("kubefirst-checks", "")
-		viper.Set("kubefirst", "")
-		viper.Set("flags", "")
-		viper.WriteConfig()
+// deleteCluster removes the k3d cluster
+func (i *k3dInstall) deleteCluster(ctx context.Context) error {
+	log.Info().Msg("destroying k3d resources with terraform")
+
+	err := k3d.DeleteK3dCluster(i.clusterName, i.config.K1Dir, i.config.K3dClient)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("k3d resources terraform destroyed")
+
+	return nil
+}
+
+// deleteGitlabSSHKey removes the gitlab ssh key provided one was created
+func (i *k3dInstall) deleteGitlabSSHKey(ctx context.Context) error {
+	gitlabClient, err := gitlab.NewGitLabClient(i.gitToken, i.gitOwner)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("attempting to delete managed ssh key...")
+	err = gitlabClient.DeleteUserSSHKey(viper.GetString("kbot.gitlab-user-based-ssh-key-title"))
+	if err != nil {
+		log.Warn().Msg(err.Error())
 	}
 
-	if _, err := os.Stat(config.K1Dir + "/kubeconfig"); !os.IsNotExist(err) {
-		err = os.Remove(config.K1Dir + "/kubeconfig")
+	return nil
+}
+
+// resetLocalContent removes local content and the kubefirst config for re-execution
+func (i *k3dInstall) resetLocalContent(ctx context.Context) error {
+	log.Info().Msg("removing previous platform content")
+
+	err := pkg.ResetK1Dir(i.config.K1Dir)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("previous platform content removed")
+
+	log.Info().Msg("resetting `$HOME/.kubefirst` config")
+	viper.Set("argocd", "")
+	viper.Set(i.gitProvider, "")
+	viper.Set("components", "")
+	viper.Set("kbot", "")
+	viper.Set("kubefirst-checks", "")
+	viper.Set("kubefirst", "")
+	viper.Set("flags", "")
+	viper.WriteConfig()
+
+	return nil
+}
+
+// removeKubeconfig deletes the kubeconfig of the cluster
+func (i *k3dInstall) removeKubeconfig(ctx context.Context) error {
+	kubeconfigPath := i.config.K1Dir + "/kubeconfig"
+	if _, err := os.Stat(kubeconfigPath); !os.IsNotExist(err) {
+		err = os.Remove(kubeconfigPath)
 		if err != nil {
-			return fmt.Errorf("unable to delete %q folder, error: %s", config.K1Dir+"/kubeconfig", err)
+			return fmt.Errorf("unable to delete %q folder, error: %s", kubeconfigPath, err)
 		}
 	}
-	time.Sleep(time.Millisecond * 200) // allows progress bars to finish
-	fmt.Printf("Your kubefirst platform running in %s has been destroyed.", k3d.CloudProvider)
-	progress.Progress.Quit()
 
 	return nil
 }
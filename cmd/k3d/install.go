@@ -0,0 +1,1115 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k3d
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	argocdapi "github.com/argoproj/argo-cd/v2/pkg/client/clientset/versioned"
+	"github.com/atotto/clipboard"
+	"github.com/go-git/go-git/v5"
+	githttps "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/kubefirst/kubefirst/internal/bundle"
+	"github.com/kubefirst/kubefirst/internal/gitShim"
+	"github.com/kubefirst/kubefirst/internal/steps"
+	"github.com/kubefirst/kubefirst/internal/tools"
+	"github.com/kubefirst/kubefirst/internal/utilities"
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+	"github.com/kubefirst/runtime/configs"
+	"github.com/kubefirst/runtime/pkg"
+	"github.com/kubefirst/runtime/pkg/argocd"
+	"github.com/kubefirst/runtime/pkg/gitClient"
+	"github.com/kubefirst/runtime/pkg/helpers"
+	"github.com/kubefirst/runtime/pkg/k3d"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	internalssh "github.com/kubefirst/runtime/pkg/ssh"
+	"github.com/kubefirst/runtime/pkg/terraform"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// k3dInstall holds the state shared by the steps of a k3d install and destroy
+type k3dInstall struct {
+	// Flags
+	clusterName          string
+	clusterType          string
+	gitProvider          string
+	gitProtocol          string
+	gitlabGroup          string
+	gitopsTemplateURL    string
+	gitopsTemplateBranch string
+	useTelemetry         bool
+
+	config     *k3d.K3dConfig
+	httpClient *http.Client
+	kcfg       *k8s.KubernetesClient
+
+	// Git provider
+	gitHost               string
+	gitOwner              string
+	gitUser               string
+	gitToken              string
+	gitlabOwnerGroupID    int
+	containerRegistryHost string
+	httpAuth              *githttps.BasicAuth
+	// gitNames are the repositories and teams the git terraform creates
+	gitNames utilities.GitNames
+
+	atlantisWebhookSecret  string
+	gitopsRepoURL          string
+	gitopsDirectoryTokens  *k3d.GitopsDirectoryValues
+	metaphorTemplateTokens *k3d.MetaphorTokenValues
+	// bundle replaces downloads when set
+	bundle *bundle.Bundle
+
+	// Set by earlier steps
+	containerRegistryAuthToken string
+	argocdPassword             string
+	vaultRootToken             string
+	kubernetesAPIEndpoint      string
+
+	// portForwards holds the stop channel of every open port forward by pod name
+	portForwards map[string]chan struct{}
+}
+
+// newK3dInstallGraph returns the steps of a k3d install in the order they run, the
+// graph only reads the git provider of install until it is run
+func newK3dInstallGraph(install *k3dInstall, segClient *telemetry.SegmentClient) *steps.Graph {
+	graph := steps.NewGraph(segClient)
+
+	err := graph.Add(
+		steps.Step{
+			Name:        "git-credentials",
+			Description: "Verifying git credentials",
+			Check:       fmt.Sprintf("%s-credentials", install.gitProvider),
+			Metrics: steps.Metrics{
+				Started:   telemetry.GitCredentialsCheckStarted,
+				Completed: telemetry.GitCredentialsCheckCompleted,
+				Failed:    telemetry.GitCredentialsCheckFailed,
+			},
+			Run:      install.checkGitCredentials,
+			Rollback: steps.Forget,
+		},
+		steps.Step{
+			Name:        "kbot-setup",
+			Description: "Creating the kbot ssh key pair",
+			DependsOn:   []string{"git-credentials"},
+			Check:       "kbot-setup",
+			Metrics: steps.Metrics{
+				Started:   telemetry.KbotSetupStarted,
+				Completed: telemetry.KbotSetupCompleted,
+				Failed:    telemetry.KbotSetupFailed,
+			},
+			Run:      install.setupKbot,
+			Rollback: steps.Forget,
+		},
+		steps.Step{
+			Name:        "environment-validated",
+			Description: "Running preflight checks",
+			DependsOn:   []string{"kbot-setup"},
+			Metrics:     steps.Metrics{Completed: telemetry.InitCompleted},
+			Run: func(ctx context.Context) error {
+				log.Info().Msg("validation and kubefirst cli environment check is complete")
+				return nil
+			},
+		},
+		steps.Step{
+			Name:        "tools-downloaded",
+			Description: "Downloading tools",
+			DependsOn:   []string{"environment-validated"},
+			Check:       "tools-downloaded",
+			Run:         install.downloadTools,
+		},
+		steps.Step{
+			Name:        "gitops-ready-to-push",
+			Description: "Cloning and formatting git repositories",
+			DependsOn:   []string{"tools-downloaded"},
+			Check:       "gitops-ready-to-push",
+			Run:         install.prepareGitRepositories,
+		},
+		steps.Step{
+			Name:        "terraform-apply-git",
+			Description: fmt.Sprintf("Applying %s Terraform", install.gitProvider),
+			DependsOn:   []string{"kbot-setup", "gitops-ready-to-push"},
+			Check:       fmt.Sprintf("terraform-apply-%s", install.gitProvider),
+			Metrics: steps.Metrics{
+				Started:   telemetry.GitTerraformApplyStarted,
+				Completed: telemetry.GitTerraformApplyCompleted,
+				Failed:    telemetry.GitTerraformApplyFailed,
+			},
+			Run:      install.applyGitTerraform,
+			Rollback: install.rollbackGitTerraform,
+		},
+		steps.Step{
+			Name:        "gitops-repo-pushed",
+			Description: "Pushing git repositories",
+			DependsOn:   []string{"terraform-apply-git"},
+			Check:       "gitops-repo-pushed",
+			Metrics: steps.Metrics{
+				Started:   telemetry.GitopsRepoPushStarted,
+				Completed: telemetry.GitopsRepoPushCompleted,
+				Failed:    telemetry.GitopsRepoPushFailed,
+			},
+			Run:      install.pushGitRepositories,
+			UndoneBy: "terraform-apply-git",
+		},
+		steps.Step{
+			Name:        "create-k3d-cluster",
+			Description: "Creating k3d cluster",
+			DependsOn:   []string{"gitops-repo-pushed"},
+			Check:       "create-k3d-cluster",
+			Metrics: steps.Metrics{
+				Started:   telemetry.CloudTerraformApplyStarted,
+				Completed: telemetry.CloudTerraformApplyCompleted,
+				Failed:    telemetry.CloudTerraformApplyFailed,
+			},
+			Run:      install.createCluster,
+			Rollback: install.deleteCluster,
+			// the git terraform state is moved into the cluster by post-detokenize
+			RollbackAfter: []string{"terraform-apply-git"},
+		},
+		steps.Step{
+			Name:        "k8s-secrets-created",
+			Description: "Bootstrapping Kubernetes resources",
+			DependsOn:   []string{"create-k3d-cluster"},
+			Check:       "k8s-secrets-created",
+			Run:         install.createSecrets,
+			UndoneBy:    "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "container-registry-auth",
+			Description: "Creating container registry credentials",
+			DependsOn:   []string{"create-k3d-cluster"},
+			Run:         install.createContainerRegistrySecret,
+		},
+		steps.Step{
+			Name:        "cluster-ready",
+			Description: "Verifying Kubernetes cluster is ready",
+			DependsOn:   []string{"create-k3d-cluster"},
+			Run:         install.waitForCluster,
+		},
+		steps.Step{
+			Name:        "argocd-install",
+			Description: "Installing Argo CD",
+			DependsOn:   []string{"cluster-ready"},
+			Check:       "argocd-install",
+			Metrics: steps.Metrics{
+				Started:   telemetry.ArgoCDInstallStarted,
+				Completed: telemetry.ArgoCDInstallCompleted,
+				Failed:    telemetry.ArgoCDInstallFailed,
+			},
+			Run:      install.installArgoCD,
+			UndoneBy: "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "argocd-ready",
+			Description: "Waiting for Argo CD",
+			DependsOn:   []string{"argocd-install"},
+			Run:         install.waitForArgoCD,
+		},
+		steps.Step{
+			Name:        "argocd-credentials-set",
+			Description: "Setting Argo CD credentials",
+			DependsOn:   []string{"argocd-ready"},
+			Check:       "argocd-credentials-set",
+			Run:         install.setArgoCDCredentials,
+			UndoneBy:    "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "argocd-open-console",
+			Description: "Opening Argo CD",
+			DependsOn:   []string{"argocd-credentials-set"},
+			Condition: func() bool {
+				return configs.K1Version == "development"
+			},
+			Run: install.openArgoCD,
+		},
+		steps.Step{
+			Name:        "argocd-create-registry",
+			Description: "Creating the Argo CD registry application",
+			DependsOn:   []string{"argocd-credentials-set"},
+			Check:       "argocd-create-registry",
+			Metrics: steps.Metrics{
+				Started:   telemetry.CreateRegistryStarted,
+				Completed: telemetry.CreateRegistryCompleted,
+				Failed:    telemetry.CreateRegistryFailed,
+			},
+			Run:      install.createRegistry,
+			UndoneBy: "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "vault-ready",
+			Description: "Waiting for Vault",
+			DependsOn:   []string{"argocd-create-registry"},
+			Run:         install.waitForVault,
+		},
+		steps.Step{
+			Name:        "vault-initialized",
+			Description: "Initializing Vault",
+			DependsOn:   []string{"vault-ready"},
+			Check:       "vault-initialized",
+			Metrics: steps.Metrics{
+				Started:   telemetry.VaultInitializationStarted,
+				Completed: telemetry.VaultInitializationCompleted,
+				Failed:    telemetry.VaultInitializationFailed,
+			},
+			Run:      install.initializeVault,
+			UndoneBy: "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "state-store-uploaded",
+			Description: "Uploading terraform state",
+			DependsOn:   []string{"terraform-apply-git", "argocd-create-registry"},
+			Run:         install.uploadStateStore,
+		},
+		steps.Step{
+			Name:        "vault-connected",
+			Description: "Connecting to Vault",
+			DependsOn:   []string{"vault-initialized"},
+			Run:         install.connectVault,
+		},
+		steps.Step{
+			Name:        "terraform-apply-vault",
+			Description: "Applying Vault Terraform",
+			DependsOn:   []string{"vault-connected", "container-registry-auth"},
+			Check:       "terraform-apply-vault",
+			Metrics: steps.Metrics{
+				Started:   telemetry.VaultTerraformApplyStarted,
+				Completed: telemetry.VaultTerraformApplyCompleted,
+				Failed:    telemetry.VaultTerraformApplyFailed,
+			},
+			Run:      install.applyVaultTerraform,
+			UndoneBy: "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "terraform-apply-users",
+			Description: "Creating users",
+			DependsOn:   []string{"terraform-apply-vault"},
+			Check:       "terraform-apply-users",
+			Metrics: steps.Metrics{
+				Started:   telemetry.UsersTerraformApplyStarted,
+				Completed: telemetry.UsersTerraformApplyCompleted,
+				Failed:    telemetry.UsersTerraformApplyFailed,
+			},
+			Run:      install.applyUsersTerraform,
+			UndoneBy: "create-k3d-cluster",
+		},
+		steps.Step{
+			Name:        "post-detokenize",
+			Description: "Pushing the detokenized gitops repository",
+			DependsOn:   []string{"terraform-apply-users"},
+			Check:       "post-detokenize",
+			AlwaysRun:   true,
+			Run:         install.pushDetokenizedGitopsRepository,
+			UndoneBy:    "terraform-apply-git",
+		},
+		steps.Step{
+			Name:        "argo-workflows-ready",
+			Description: "Waiting for Argo Workflows",
+			DependsOn:   []string{"post-detokenize"},
+			Run:         install.waitForArgoWorkflows,
+		},
+		steps.Step{
+			Name:        "cluster-install-complete",
+			Description: "Wrapping up",
+			DependsOn:   []string{"argo-workflows-ready"},
+			Check:       "cluster-install-complete",
+			Metrics:     steps.Metrics{Completed: telemetry.ClusterInstallCompleted},
+			Run:         install.exportCluster,
+			UndoneBy:    "create-k3d-cluster",
+		},
+	)
+	if err != nil {
+		// the steps above are static, an error here is a programming mistake
+		log.Panic().Msgf("invalid k3d install graph: %s", err)
+	}
+
+	return graph
+}
+
+// kubeConfig returns a client for the k3d cluster, it can only be used once the cluster exists
+func (i *k3dInstall) kubeConfig() *k8s.KubernetesClient {
+	if i.kcfg == nil {
+		i.kcfg = k8s.CreateKubeConfig(false, i.config.Kubeconfig)
+	}
+
+	return i.kcfg
+}
+
+// openPortForward forwards port to a pod until closePortForwards is called, a pod
+// that is already forwarded is left as is
+func (i *k3dInstall) openPortForward(podName string, namespace string, port int) {
+	if _, open := i.portForwards[podName]; open {
+		return
+	}
+	if i.portForwards == nil {
+		i.portForwards = map[string]chan struct{}{}
+	}
+
+	kcfg := i.kubeConfig()
+
+	stopChannel := make(chan struct{}, 1)
+	i.portForwards[podName] = stopChannel
+	k8s.OpenPortForwardPodWrapper(
+		kcfg.Clientset,
+		kcfg.RestConfig,
+		podName,
+		namespace,
+		port,
+		port,
+		stopChannel,
+	)
+}
+
+// closePortForwards stops every port forward opened by the steps
+func (i *k3dInstall) closePortForwards() {
+	for podName, stopChannel := range i.portForwards {
+		close(stopChannel)
+		delete(i.portForwards, podName)
+	}
+}
+
+// checkGitCredentials verifies the git token and that the new repositories and teams do not exist yet
+func (i *k3dInstall) checkGitCredentials(ctx context.Context) error {
+	if len(i.gitToken) == 0 {
+		return fmt.Errorf(
+			"please set a %s_TOKEN environment variable to continue",
+			strings.ToUpper(i.config.GitProvider),
+		)
+	}
+
+	// Repositories that will be created throughout the initialization process
+	initGitParameters := gitShim.GitInitParameters{
+		GitProvider:  i.gitProvider,
+		GitToken:     i.gitToken,
+		GitOwner:     i.gitOwner,
+		Repositories: i.gitNames.Repositories(),
+		Teams:        i.gitNames.Teams(),
+	}
+
+	return gitShim.InitializeGitProvider(&initGitParameters)
+}
+
+// setupKbot creates the ssh key pair of the kbot user
+func (i *k3dInstall) setupKbot(ctx context.Context) error {
+	log.Info().Msg("creating an ssh key pair for your new cloud infrastructure")
+	sshPrivateKey, sshPublicKey, err := internalssh.CreateSshKeyPair()
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("ssh key pair creation complete")
+
+	viper.Set("kbot.private-key", sshPrivateKey)
+	viper.Set("kbot.public-key", sshPublicKey)
+	viper.Set("kbot.username", "kbot")
+	viper.WriteConfig()
+	log.Info().Msg("kbot-setup complete")
+
+	return nil
+}
+
+// downloadTools installs the dependencies to `$HOME/.k1/tools`
+func (i *k3dInstall) downloadTools(ctx context.Context) error {
+	log.Info().Msg("installing kubefirst dependencies")
+
+	for _, tool := range []tools.Tool{tools.K3d, tools.Kubectl, tools.Mkcert, tools.Terraform} {
+		var err error
+		switch {
+		case tool.Current(i.config.ToolsDir):
+			log.Info().Msgf("%s %s is already installed, continuing", tool.Name, tool.Version)
+		case i.bundle != nil:
+			err = i.bundle.InstallTool(tool, i.config.ToolsDir)
+		default:
+			err = tool.Download(i.config.ToolsDir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("download dependencies `$HOME/.k1/tools` complete")
+
+	return nil
+}
+
+// prepareGitRepositories clones and detokenizes the gitops and metaphor repositories
+func (i *k3dInstall) prepareGitRepositories(ctx context.Context) error {
+	log.Info().Msg("generating your new gitops repository")
+
+	gitopsTemplateURL := i.gitopsTemplateURL
+	if i.bundle != nil {
+		gitopsTemplateURL = i.bundle.GitopsTemplatePath()
+	}
+
+	err := k3d.PrepareGitRepositories(
+		i.config.GitProvider,
+		i.clusterName,
+		i.clusterType,
+		i.config.DestinationGitopsRepoURL, //default to https for git interactions when creating remotes
+		i.config.GitopsDir,
+		i.gitopsTemplateBranch,
+		gitopsTemplateURL,
+		i.config.DestinationMetaphorRepoURL, //default to https for git interactions when creating remotes
+		i.config.K1Dir,
+		i.gitopsDirectoryTokens,
+		i.config.MetaphorDir,
+		i.metaphorTemplateTokens,
+		i.gitProtocol,
+	)
+	if err != nil {
+		return err
+	}
+
+	// the git terraform takes the names as variables, a template that does not declare
+	// them would create the repositories and teams with the default names
+	if i.gitNames != utilities.DefaultGitNames {
+		return i.gitNames.CheckTerraformVariables(filepath.Join(i.config.GitopsDir, "terraform", i.config.GitProvider))
+	}
+
+	return nil
+}
+
+// applyGitTerraform creates the teams and repositories in the git provider
+func (i *k3dInstall) applyGitTerraform(ctx context.Context) error {
+	tfEntrypoint := fmt.Sprintf("%s/terraform/%s", i.config.GitopsDir, i.config.GitProvider)
+	tfEnvs := map[string]string{}
+
+	switch i.config.GitProvider {
+	case "github":
+		log.Info().Msg("Creating GitHub resources with Terraform")
+		tfEnvs["GITHUB_TOKEN"] = i.gitToken
+		tfEnvs["GITHUB_OWNER"] = i.gitOwner
+	case "gitlab":
+		log.Info().Msg("Creating GitLab resources with Terraform")
+		tfEnvs["GITLAB_TOKEN"] = i.gitToken
+		tfEnvs["GITLAB_OWNER"] = i.gitlabGroup
+		tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(i.gitlabOwnerGroupID)
+	}
+	tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
+	tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
+	tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
+	tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
+	tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
+	for key, value := range i.gitNames.TerraformEnvs() {
+		tfEnvs[key] = value
+	}
+	// Erase public key to prevent it from being created if the git protocol argument is set to htps
+	switch i.config.GitProtocol {
+	case "https":
+		tfEnvs["TF_VAR_kbot_ssh_public_key"] = ""
+	}
+
+	err := terraform.InitApplyAutoApprove(i.config.TerraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		return fmt.Errorf("error creating %s resources with terraform %s: %s", i.config.GitProvider, tfEntrypoint, err)
+	}
+
+	switch i.config.GitProvider {
+	case "github":
+		log.Info().Msgf("created git repositories for github.com/%s", i.gitOwner)
+	case "gitlab":
+		log.Info().Msgf("created git projects and groups for gitlab.com/%s", i.gitlabGroup)
+	}
+
+	return nil
+}
+
+// pushGitRepositories pushes the detokenized gitops-template repository content to the new remotes
+func (i *k3dInstall) pushGitRepositories(ctx context.Context) error {
+	log.Info().Msgf("referencing gitops repository: %s", i.config.DestinationGitopsRepoGitURL)
+	log.Info().Msgf("referencing metaphor repository: %s", i.config.DestinationMetaphorRepoURL)
+
+	gitopsRepo, err := git.PlainOpen(i.config.GitopsDir)
+	if err != nil {
+		return fmt.Errorf("error opening repo at %s: %s", i.config.GitopsDir, err)
+	}
+
+	metaphorRepo, err := git.PlainOpen(i.config.MetaphorDir)
+	if err != nil {
+		return fmt.Errorf("error opening repo at %s: %s", i.config.MetaphorDir, err)
+	}
+
+	err = internalssh.EvalSSHKey(&internalssh.EvalSSHKeyRequest{
+		GitProvider:     i.gitProvider,
+		GitlabGroupFlag: i.gitlabGroup,
+		GitToken:        i.gitToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	//Push to remotes and use https
+	// Push gitops repo to remote
+	err = gitopsRepo.Push(
+		&git.PushOptions{
+			RemoteName: i.config.GitProvider,
+			Auth:       i.httpAuth,
+		},
+	)
+	if err != nil && !strings.Contains(err.Error(), "already up-to-date") {
+		return fmt.Errorf("error pushing detokenized gitops repository to remote %s: %s", i.config.DestinationGitopsRepoGitURL, err)
+	}
+
+	// push metaphor repo to remote
+	err = metaphorRepo.Push(
+		&git.PushOptions{
+			RemoteName: "origin",
+			Auth:       i.httpAuth,
+		},
+	)
+	if err != nil && !strings.Contains(err.Error(), "already up-to-date") {
+		return fmt.Errorf("error pushing detokenized metaphor repository to remote %s: %s", i.config.DestinationMetaphorRepoURL, err)
+	}
+	log.Info().Msgf("successfully pushed gitops and metaphor repositories to https://%s/%s", i.gitHost, i.gitOwner)
+
+	// todo delete the local gitops repo and re-clone it
+	// todo that way we can stop worrying about which origin we're going to push to
+	return nil
+}
+
+// createCluster creates the k3d cluster
+func (i *k3dInstall) createCluster(ctx context.Context) error {
+	log.Info().Msg("Creating k3d cluster")
+
+	err := k3d.ClusterCreate(i.clusterName, i.config.K1Dir, i.config.K3dClient, i.config.Kubeconfig)
+	if err != nil {
+		viper.Set("kubefirst-checks.create-k3d-cluster-failed", true)
+		viper.WriteConfig()
+		return fmt.Errorf("error creating k3d resources with k3d client %s: %s", i.config.K3dClient, err)
+	}
+
+	log.Info().Msg("successfully created k3d cluster")
+
+	return nil
+}
+
+// createSecrets bootstraps the kubernetes secrets
+func (i *k3dInstall) createSecrets(ctx context.Context) error {
+	err := k3d.GenerateTLSSecrets(i.kubeConfig().Clientset, *i.config)
+	if err != nil {
+		return err
+	}
+
+	err = k3d.AddK3DSecrets(
+		i.atlantisWebhookSecret,
+		viper.GetString("kbot.public-key"),
+		i.gitopsRepoURL,
+		viper.GetString("kbot.private-key"),
+		i.config.GitProvider,
+		i.gitUser,
+		i.gitOwner,
+		i.config.Kubeconfig,
+		i.gitToken,
+	)
+	if err != nil {
+		log.Info().Msg("Error adding kubernetes secrets for bootstrap")
+		return err
+	}
+
+	return nil
+}
+
+// createContainerRegistrySecret creates the container registry authentication
+func (i *k3dInstall) createContainerRegistrySecret(ctx context.Context) error {
+	containerRegistryAuth := gitShim.ContainerRegistryAuth{
+		GitProvider:           i.gitProvider,
+		GitUser:               i.gitUser,
+		GitToken:              i.gitToken,
+		GitlabGroupFlag:       i.gitlabGroup,
+		GithubOwner:           i.gitOwner,
+		ContainerRegistryHost: i.containerRegistryHost,
+		Clientset:             i.kubeConfig().Clientset,
+	}
+
+	token, err := gitShim.CreateContainerRegistrySecret(&containerRegistryAuth)
+	if err != nil {
+		return err
+	}
+	i.containerRegistryAuthToken = token
+
+	return nil
+}
+
+// waitForCluster runs the k3d readiness checks
+func (i *k3dInstall) waitForCluster(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	// traefik
+	traefikDeployment, err := k8s.ReturnDeploymentObject(
+		kcfg.Clientset,
+		"app.kubernetes.io/name",
+		"traefik",
+		"kube-system",
+		240,
+	)
+	if err != nil {
+		return fmt.Errorf("error finding traefik deployment: %s", err)
+	}
+	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, traefikDeployment, 240)
+	if err != nil {
+		return fmt.Errorf("error waiting for traefik deployment ready state: %s", err)
+	}
+
+	// metrics-server
+	metricsServerDeployment, err := k8s.ReturnDeploymentObject(
+		kcfg.Clientset,
+		"k8s-app",
+		"metrics-server",
+		"kube-system",
+		240,
+	)
+	if err != nil {
+		return fmt.Errorf("error finding metrics-server deployment: %s", err)
+	}
+	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, metricsServerDeployment, 240)
+	if err != nil {
+		return fmt.Errorf("error waiting for metrics-server deployment ready state: %s", err)
+	}
+
+	time.Sleep(time.Second * 20)
+
+	return nil
+}
+
+// installArgoCD applies the argocd manifests
+func (i *k3dInstall) installArgoCD(ctx context.Context) error {
+	log.Info().Msgf("installing argocd")
+
+	output, err := i.buildManifest(bundle.ArgoCDManifest)
+	if err != nil {
+		return err
+	}
+
+	return i.kubeConfig().ApplyObjects("", output)
+}
+
+// buildManifest renders a kustomization, or reads it from the bundle where it was rendered ahead of time
+func (i *k3dInstall) buildManifest(name string) ([][]byte, error) {
+	kcfg := i.kubeConfig()
+
+	var yamlData *bytes.Buffer
+	var err error
+	if i.bundle != nil {
+		yamlData, err = i.bundle.ReadManifest(name)
+	} else {
+		yamlData, err = kcfg.KustomizeBuild(bundle.Kustomizations[name])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return kcfg.SplitYAMLFile(yamlData)
+}
+
+// waitForArgoCD waits for the argocd pods to be ready
+func (i *k3dInstall) waitForArgoCD(ctx context.Context) error {
+	_, err := k8s.VerifyArgoCDReadiness(i.kubeConfig().Clientset, true, 300)
+	if err != nil {
+		return fmt.Errorf("error waiting for ArgoCD to become ready: %s", err)
+	}
+
+	return nil
+}
+
+// setArgoCDCredentials stores the argocd admin credentials and an auth token
+func (i *k3dInstall) setArgoCDCredentials(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	log.Info().Msg("Setting argocd username and password credentials")
+
+	argocd.ArgocdSecretClient = kcfg.Clientset.CoreV1().Secrets("argocd")
+
+	i.argocdPassword = k8s.GetSecretValue(argocd.ArgocdSecretClient, "argocd-initial-admin-secret", "password")
+	if i.argocdPassword == "" {
+		return fmt.Errorf("argocd password not found in secret")
+	}
+
+	viper.Set("components.argocd.password", i.argocdPassword)
+	viper.Set("components.argocd.username", "admin")
+	viper.WriteConfig()
+	log.Info().Msg("argocd username and password credentials set successfully")
+	log.Info().Msg("Getting an argocd auth token")
+
+	// Test https to argocd
+	var argoCDToken string
+	// only the host, not the protocol
+	err := helpers.TestEndpointTLS(strings.Replace(k3d.ArgocdURL, "https://", "", 1))
+	if err != nil {
+		argoCDStopChannel := make(chan struct{}, 1)
+		log.Info().Msgf("argocd not available via https, using http")
+		defer func() {
+			close(argoCDStopChannel)
+		}()
+		k8s.OpenPortForwardPodWrapper(
+			kcfg.Clientset,
+			kcfg.RestConfig,
+			"argocd-server",
+			"argocd",
+			8080,
+			8080,
+			argoCDStopChannel,
+		)
+		argoCDHTTPURL := strings.Replace(
+			k3d.ArgocdURL,
+			"https://",
+			"http://",
+			1,
+		) + ":8080"
+		argoCDToken, err = argocd.GetArgocdTokenV2(i.httpClient, argoCDHTTPURL, "admin", i.argocdPassword)
+		if err != nil {
+			return err
+		}
+	} else {
+		argoCDToken, err = argocd.GetArgocdTokenV2(i.httpClient, k3d.ArgocdURL, "admin", i.argocdPassword)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msg("argocd admin auth token set")
+
+	viper.Set("components.argocd.auth-token", argoCDToken)
+	viper.WriteConfig()
+
+	return nil
+}
+
+// openArgoCD copies the argocd password and opens the console, it only runs in development builds
+func (i *k3dInstall) openArgoCD(ctx context.Context) error {
+	err := clipboard.WriteAll(i.argocdPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	err = pkg.OpenBrowser(pkg.ArgoCDLocalURLTLS)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	return nil
+}
+
+// createRegistry applies the registry application to start the argocd sync waves
+func (i *k3dInstall) createRegistry(ctx context.Context) error {
+	argocdClient, err := argocdapi.NewForConfig(i.kubeConfig().RestConfig)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("applying the registry application to argocd")
+	registryApplicationObject := argocd.GetArgoCDApplicationObject(i.gitopsRepoURL, fmt.Sprintf("registry/%s", i.clusterName))
+
+	_, _ = argocdClient.ArgoprojV1alpha1().Applications("argocd").Create(context.Background(), registryApplicationObject, metav1.CreateOptions{})
+
+	return nil
+}
+
+// waitForVault waits for the vault statefulset pods to transition to running
+func (i *k3dInstall) waitForVault(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	vaultStatefulSet, err := k8s.ReturnStatefulSetObject(
+		kcfg.Clientset,
+		"app.kubernetes.io/instance",
+		"vault",
+		"vault",
+		120,
+	)
+	if err != nil {
+		return fmt.Errorf("error finding Vault StatefulSet: %s", err)
+	}
+	_, err = k8s.WaitForStatefulSetReady(kcfg.Clientset, vaultStatefulSet, 120, true)
+	if err != nil {
+		return fmt.Errorf("error waiting for Vault StatefulSet ready state: %s", err)
+	}
+
+	// Init and unseal vault
+	// We need to wait before we try to run any of these commands or there may be
+	// unexpected timeouts
+	time.Sleep(time.Second * 10)
+
+	return nil
+}
+
+// initializeVault initializes and unseals vault
+func (i *k3dInstall) initializeVault(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	output, err := i.buildManifest(bundle.VaultHandlerManifest)
+	if err != nil {
+		return err
+	}
+	err = kcfg.ApplyObjects("", output)
+	if err != nil {
+		return err
+	}
+
+	// Wait for the Job to finish
+	job, err := k8s.ReturnJobObject(kcfg.Clientset, "vault", "vault-handler")
+	if err != nil {
+		return err
+	}
+	_, err = k8s.WaitForJobComplete(kcfg.Clientset, job, 240)
+	if err != nil {
+		return fmt.Errorf("could not run vault unseal job: %s", err)
+	}
+
+	return nil
+}
+
+// uploadStateStore copies the git terraform state to the in-cluster minio bucket
+func (i *k3dInstall) uploadStateStore(ctx context.Context) error {
+	i.openPortForward("minio", "minio", 9000)
+
+	// Initialize minio client object.
+	minioClient, err := minio.New(pkg.MinioPortForwardEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(pkg.MinioDefaultUsername, pkg.MinioDefaultPassword, ""),
+		Secure: false,
+		Region: pkg.MinioRegion,
+	})
+	if err != nil {
+		log.Info().Msgf("Error creating Minio client: %s", err)
+		return nil
+	}
+
+	//define upload object
+	objectName := fmt.Sprintf("terraform/%s/terraform.tfstate", i.config.GitProvider)
+	filePath := i.config.K1Dir + fmt.Sprintf("/gitops/%s", objectName)
+	contentType := "xl.meta"
+	bucketName := "kubefirst-state-store"
+	log.Info().Msgf("BucketName: %s", bucketName)
+
+	viper.Set("kubefirst.state-store.name", bucketName)
+	viper.Set("kubefirst.state-store.hostname", "minio-console.kubefirst.dev")
+	viper.Set("kubefirst.state-store-creds.access-key-id", pkg.MinioDefaultUsername)
+	viper.Set("kubefirst.state-store-creds.secret-access-key-id", pkg.MinioDefaultPassword)
+
+	// Upload the zip file with FPutObject
+	info, err := minioClient.FPutObject(ctx, bucketName, objectName, filePath, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		log.Info().Msgf("Error uploading to Minio bucket: %s", err)
+		return nil
+	}
+
+	log.Printf("Successfully uploaded %s to bucket %s\n", objectName, info.Bucket)
+
+	return nil
+}
+
+// connectVault opens the vault port forward and reads the root token
+func (i *k3dInstall) connectVault(ctx context.Context) error {
+	i.openPortForward("vault-0", "vault", 8200)
+
+	// Retrieve root token from init step
+	secData, err := k8s.ReadSecretV2(i.kubeConfig().Clientset, "vault", "vault-unseal-secret")
+	if err != nil {
+		return err
+	}
+	i.vaultRootToken = secData["root-token"]
+
+	// Parse k3d api endpoint from kubeconfig
+	// In this case, we need to get the IP of the in-cluster API server to provide to Vault
+	// to work with Kubernetes auth
+	kubernetesInClusterAPIService, err := k8s.ReadService(i.config.Kubeconfig, "default", "kubernetes")
+	if err != nil {
+		return fmt.Errorf("error looking up kubernetes api server service: %s", err)
+	}
+	i.kubernetesAPIEndpoint = fmt.Sprintf("https://%s", kubernetesInClusterAPIService.Spec.ClusterIP)
+
+	err = helpers.TestEndpointTLS(strings.Replace(k3d.VaultURL, "https://", "", 1))
+	if err != nil {
+		return fmt.Errorf(
+			"unable to reach vault over https - this is likely due to the mkcert certificate store missing. please install it via `%s -install`", i.config.MkCertClient,
+		)
+	}
+
+	return nil
+}
+
+// applyVaultTerraform configures vault with terraform
+func (i *k3dInstall) applyVaultTerraform(ctx context.Context) error {
+	tfEnvs := map[string]string{}
+	var usernamePasswordString, base64DockerAuth string
+
+	if i.config.GitProvider == "gitlab" {
+		usernamePasswordString = fmt.Sprintf("%s:%s", "container-registry-auth", i.containerRegistryAuthToken)
+		base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
+
+		tfEnvs["TF_VAR_container_registry_auth"] = i.containerRegistryAuthToken
+		tfEnvs["TF_VAR_owner_group_id"] = strconv.Itoa(i.gitlabOwnerGroupID)
+	} else {
+		usernamePasswordString = fmt.Sprintf("%s:%s", i.gitUser, i.gitToken)
+		base64DockerAuth = base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
+	}
+
+	log.Info().Msg("configuring vault with terraform")
+
+	tfEnvs["TF_VAR_email_address"] = "your@email.com"
+	tfEnvs[fmt.Sprintf("TF_VAR_%s_token", i.config.GitProvider)] = i.gitToken
+	tfEnvs[fmt.Sprintf("TF_VAR_%s_user", i.config.GitProvider)] = i.gitUser
+	tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
+	tfEnvs["TF_VAR_b64_docker_auth"] = base64DockerAuth
+	tfEnvs["TF_VAR_vault_token"] = i.vaultRootToken
+	tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
+	tfEnvs["VAULT_TOKEN"] = i.vaultRootToken
+	tfEnvs["TF_VAR_atlantis_repo_webhook_secret"] = viper.GetString("secrets.atlantis-webhook")
+	tfEnvs["TF_VAR_kbot_ssh_private_key"] = viper.GetString("kbot.private-key")
+	tfEnvs["TF_VAR_kbot_ssh_public_key"] = viper.GetString("kbot.public-key")
+	tfEnvs["TF_VAR_kubernetes_api_endpoint"] = i.kubernetesAPIEndpoint
+	tfEnvs[fmt.Sprintf("%s_OWNER", strings.ToUpper(i.config.GitProvider))] = viper.GetString(fmt.Sprintf("flags.%s-owner", i.config.GitProvider))
+	tfEnvs["AWS_ACCESS_KEY_ID"] = pkg.MinioDefaultUsername
+	tfEnvs["AWS_SECRET_ACCESS_KEY"] = pkg.MinioDefaultPassword
+	tfEnvs["TF_VAR_aws_access_key_id"] = pkg.MinioDefaultUsername
+	tfEnvs["TF_VAR_aws_secret_access_key"] = pkg.MinioDefaultPassword
+	// tfEnvs["TF_LOG"] = "DEBUG"
+
+	tfEntrypoint := i.config.GitopsDir + "/terraform/vault"
+	err := terraform.InitApplyAutoApprove(i.config.TerraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msg("vault terraform executed successfully")
+
+	return nil
+}
+
+// applyUsersTerraform creates the users with terraform
+func (i *k3dInstall) applyUsersTerraform(ctx context.Context) error {
+	log.Info().Msg("applying users terraform")
+
+	tfEnvs := map[string]string{}
+	tfEnvs["TF_VAR_email_address"] = "your@email.com"
+	tfEnvs[fmt.Sprintf("TF_VAR_%s_token", i.config.GitProvider)] = i.gitToken
+	tfEnvs["TF_VAR_vault_addr"] = k3d.VaultPortForwardURL
+	tfEnvs["TF_VAR_vault_token"] = i.vaultRootToken
+	tfEnvs["VAULT_ADDR"] = k3d.VaultPortForwardURL
+	tfEnvs["VAULT_TOKEN"] = i.vaultRootToken
+	tfEnvs[fmt.Sprintf("%s_TOKEN", strings.ToUpper(i.config.GitProvider))] = i.gitToken
+	tfEnvs[fmt.Sprintf("%s_OWNER", strings.ToUpper(i.config.GitProvider))] = i.gitOwner
+
+	tfEntrypoint := i.config.GitopsDir + "/terraform/users"
+	err := terraform.InitApplyAutoApprove(i.config.TerraformClient, tfEntrypoint, tfEnvs)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg("executed users terraform successfully")
+
+	return nil
+}
+
+// pushDetokenizedGitopsRepository replaces the post run tokens and commits and pushes the gitops repository
+func (i *k3dInstall) pushDetokenizedGitopsRepository(ctx context.Context) error {
+	err := k3d.PostRunPrepareGitopsRepository(i.clusterName,
+		i.config.GitopsDir,
+		i.gitopsDirectoryTokens,
+	)
+	if err != nil {
+		log.Info().Msgf("Error detokenize post run: %s", err)
+	}
+	gitopsRepo, err := git.PlainOpen(i.config.GitopsDir)
+	if err != nil {
+		return fmt.Errorf("error opening repo at %s: %s", i.config.GitopsDir, err)
+	}
+	//check if file exists before rename
+	remoteBackend := fmt.Sprintf("%s/terraform/%s/remote-backend", i.config.GitopsDir, i.config.GitProvider)
+	_, err = os.Stat(remoteBackend + ".md")
+	if err == nil {
+		err = os.Rename(remoteBackend+".md", remoteBackend+".tf")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = gitClient.Commit(gitopsRepo, "committing initial detokenized gitops-template repo content post run")
+	if err != nil {
+		return err
+	}
+	err = gitopsRepo.Push(&git.PushOptions{
+		RemoteName: i.config.GitProvider,
+		Auth:       i.httpAuth,
+	})
+	if err != nil {
+		log.Info().Msgf("Error pushing repo: %s", err)
+	}
+
+	return nil
+}
+
+// waitForArgoWorkflows waits for the argo workflows deployment to transition to running
+func (i *k3dInstall) waitForArgoWorkflows(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	argoDeployment, err := k8s.ReturnDeploymentObject(
+		kcfg.Clientset,
+		"app.kubernetes.io/instance",
+		"argo",
+		"argo",
+		1200,
+	)
+	if err != nil {
+		return fmt.Errorf("error finding argo workflows Deployment: %s", err)
+	}
+	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, argoDeployment, 120)
+	if err != nil {
+		return fmt.Errorf("error waiting for argo workflows Deployment ready state: %s", err)
+	}
+
+	return nil
+}
+
+// exportCluster hands the cluster record over to the in-cluster kubefirst api
+func (i *k3dInstall) exportCluster(ctx context.Context) error {
+	kcfg := i.kubeConfig()
+
+	// Set flags used to track status of active options
+	helpers.SetClusterStatusFlags(k3d.CloudProvider, i.config.GitProvider)
+
+	cluster := utilities.CreateClusterRecordFromRaw(i.useTelemetry, i.gitOwner, i.gitUser, i.gitToken, i.gitlabOwnerGroupID, i.gitopsTemplateURL, i.gitopsTemplateBranch)
+
+	err := utilities.ExportCluster(cluster, kcfg)
+	if err != nil {
+		log.Error().Err(err).Msg("error exporting cluster object")
+		viper.Set("kubefirst.setup-complete", false)
+		viper.WriteConfig()
+		return err
+	}
+
+	kubefirstDeployment, err := k8s.ReturnDeploymentObject(
+		kcfg.Clientset,
+		"app.kubernetes.io/instance",
+		"kubefirst",
+		"kubefirst",
+		600,
+	)
+	if err != nil {
+		return fmt.Errorf("error finding kubefirst Deployment: %s", err)
+	}
+	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, kubefirstDeployment, 120)
+	if err != nil {
+		return fmt.Errorf("error waiting for kubefirst Deployment ready state: %s", err)
+	}
+
+	err = pkg.OpenBrowser(pkg.KubefirstConsoleLocalURLTLS)
+	if err != nil {
+		log.Error().Err(err).Msg("")
+	}
+
+	return nil
+}
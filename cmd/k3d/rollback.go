@@ -0,0 +1,69 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package k3d
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/steps"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"golang.org/x/exp/slices"
+)
+
+// k3dRollbackReport is the structured output of --rollback-on-failure
+type k3dRollbackReport struct {
+	Error      string   `json:"error" yaml:"error"`
+	RolledBack []string `json:"rolled_back" yaml:"rolled_back"`
+	LeftOver   []string `json:"left_over" yaml:"left_over"`
+}
+
+// rollbackK3d undoes the completed steps of a failed install, last step first, and
+// reports what was removed and what is left over
+func rollbackK3d(ctx context.Context, graph *steps.Graph, installErr error) error {
+	log.Error().Msgf("k3d install failed, rolling back completed steps: %s", installErr)
+
+	report := graph.Rollback(ctx)
+
+	// the cluster of a failed create is gone as well
+	if slices.Contains(report.RolledBack, "create-k3d-cluster") {
+		viper.Set("kubefirst-checks.create-k3d-cluster-failed", false)
+		viper.WriteConfig()
+	}
+
+	if progress.IsStructuredOutput() {
+		err := progress.Document(k3dRollbackReport{
+			Error:      installErr.Error(),
+			RolledBack: report.RolledBack,
+			LeftOver:   report.LeftOver,
+		})
+		if err != nil {
+			log.Error().Msgf("unable to write rollback report: %s", err)
+		}
+	} else {
+		out := progress.MessageWriter()
+		fmt.Fprintf(out, "\nrolled back after a failed install: %s\n", installErr)
+		fmt.Fprintf(out, "  removed:   %s\n", formatStepNames(report.RolledBack))
+		fmt.Fprintf(out, "  left over: %s\n", formatStepNames(report.LeftOver))
+		if len(report.LeftOver) > 0 {
+			fmt.Fprintf(out, "run `kubefirst k3d destroy` to remove what is left over\n")
+		}
+	}
+
+	return fmt.Errorf("%s - rolled back %d steps, %d left over", installErr, len(report.RolledBack), len(report.LeftOver))
+}
+
+func formatStepNames(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
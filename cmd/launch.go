@@ -16,6 +16,26 @@ import (
 var (
 	// additionalHelmFlags can optionally pass user-supplied flags to helm
 	additionalHelmFlags []string
+	// bundleFlag installs the console from a bundle created with `kubefirst tools bundle`
+	bundleFlag string
+	// chartVersionFlag is the console chart version launch upgrade moves to
+	chartVersionFlag string
+	// valuesFilesFlag are helm values files applied to the console chart
+	valuesFilesFlag []string
+	// profileFlag is the launch profile launch up installs
+	profileFlag string
+	// databaseFlag is the database destination launch up installs or a launch profile selects
+	databaseFlag string
+	// atlas flags answer the MongoDB Atlas prompts of launch up
+	atlasHostFlag         string
+	atlasUserFlag         string
+	atlasPasswordFileFlag string
+	// mongodbURIFlag is the connection string of an external database for launch up
+	mongodbURIFlag string
+	// backup flags are the backup file and the file holding its passphrase
+	backupFileFlag     string
+	backupInputFlag    string
+	passphraseFileFlag string
 )
 
 func LaunchCommand() *cobra.Command {
@@ -26,7 +46,7 @@ func LaunchCommand() *cobra.Command {
 	}
 
 	// wire up new commands
-	launchCommand.AddCommand(launchUp(), launchDown(), launchCluster())
+	launchCommand.AddCommand(launchUp(), launchUpgrade(), launchStatus(), launchBackup(), launchRestore(), launchDown(), launchCluster(), launchProfile())
 
 	return launchCommand
 }
@@ -39,15 +59,108 @@ func launchUp() *cobra.Command {
 		TraverseChildren: true,
 		// PreRun:           common.CheckDocker, // TODO: check runtimes when we can support more runtimes
 		Run: func(cmd *cobra.Command, args []string) {
-			launch.Up(additionalHelmFlags, false, true)
+			launch.Up(launch.UpOptions{
+				AdditionalHelmFlags: additionalHelmFlags,
+				ValuesFiles:         valuesFilesFlag,
+				Profile:             profileFlag,
+				UseTelemetry:        true,
+				BundlePath:          bundleFlag,
+				Database: launch.DatabaseOptions{
+					Destination:       databaseFlag,
+					AtlasHost:         atlasHostFlag,
+					AtlasUser:         atlasUserFlag,
+					AtlasPasswordFile: atlasPasswordFileFlag,
+					ExternalURI:       mongodbURIFlag,
+				},
+			})
 		},
 	}
 
 	launchUpCmd.Flags().StringSliceVar(&additionalHelmFlags, "helm-flag", []string{}, "additional helm flag to pass to the launch up command - can be used any number of times")
+	launchUpCmd.Flags().StringArrayVar(&valuesFilesFlag, "values", []string{}, "helm values file for the console chart, applied after the profile and before --helm-flag - can be used any number of times")
+	launchUpCmd.Flags().StringVar(&profileFlag, "profile", "", "name of a launch profile created with kubefirst launch profile create")
+	launchUpCmd.Flags().StringVar(&databaseFlag, "database", "", "the console database, in-cluster, atlas or external - overrides the profile and skips the prompt")
+	launchUpCmd.Flags().StringVar(&atlasHostFlag, "atlas-host", "", "the MongoDB Atlas host string when --database is atlas")
+	launchUpCmd.Flags().StringVar(&atlasUserFlag, "atlas-user", "", "the MongoDB Atlas username when --database is atlas")
+	launchUpCmd.Flags().StringVar(&atlasPasswordFileFlag, "atlas-password-file", "", fmt.Sprintf("file holding the MongoDB Atlas password, %s is used when it is not set", launch.AtlasPasswordEnv))
+	launchUpCmd.Flags().StringVar(&mongodbURIFlag, "mongodb-uri", "", fmt.Sprintf("the connection string with username and password when --database is external, %s is used when it is not set", launch.MongoDBURIEnv))
+	launchUpCmd.Flags().StringVar(&bundleFlag, "bundle", "", "path to a bundle created with kubefirst tools bundle to install the tools and helm chart from instead of downloading them")
 
 	return launchUpCmd
 }
 
+// launchUpgrade moves the Kubefirst console and API to another chart version in place
+func launchUpgrade() *cobra.Command {
+	launchUpgradeCmd := &cobra.Command{
+		Use:              "upgrade",
+		Short:            "upgrade the console and api to another chart version",
+		Long:             "upgrade the console and api helm release in place, keeping its values and database, and roll back if the rollout fails",
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.Upgrade(chartVersionFlag)
+		},
+	}
+
+	launchUpgradeCmd.Flags().StringVar(&chartVersionFlag, "version", "", "the console chart version to upgrade to, defaults to the version this kubefirst installs")
+
+	return launchUpgradeCmd
+}
+
+// launchStatus reports the health of the Kubefirst console cluster
+func launchStatus() *cobra.Command {
+	launchStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "check the health of the console and api instance",
+		Long: fmt.Sprintf(`check the k3d cluster, the helm release, the kubefirst api, database and traefik
+deployments, the console certificate and the console api health endpoint
+
+exits with 0 when every check passes, 1 when a check fails and %d when a check warns`, launch.StatusExitWarn),
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.Status()
+		},
+	}
+
+	return launchStatusCmd
+}
+
+// launchBackup exports the console database to an encrypted file
+func launchBackup() *cobra.Command {
+	launchBackupCmd := &cobra.Command{
+		Use:              "backup",
+		Short:            "back up the clusters the console manages to an encrypted file",
+		Long:             "export every collection of the console database, its cluster, service and environment records and gitops catalog, to a file encrypted with a passphrase, restore it into a new console with kubefirst launch restore",
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.Backup(backupFileFlag, passphraseFileFlag)
+		},
+	}
+
+	launchBackupCmd.Flags().StringVar(&backupFileFlag, "file", "kubefirst-console-backup.json", "the path of the backup file to write")
+	launchBackupCmd.Flags().StringVar(&passphraseFileFlag, "passphrase-file", "", fmt.Sprintf("file holding the backup passphrase, %s is used when it is not set", launch.BackupPassphraseEnv))
+
+	return launchBackupCmd
+}
+
+// launchRestore imports the records of a backup into the console
+func launchRestore() *cobra.Command {
+	launchRestoreCmd := &cobra.Command{
+		Use:              "restore",
+		Short:            "restore the clusters of a backup into the console",
+		Long:             "import every collection of a backup created with kubefirst launch backup into the console database, existing records with the same id are replaced",
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.Restore(backupInputFlag, passphraseFileFlag)
+		},
+	}
+
+	launchRestoreCmd.Flags().StringVar(&backupInputFlag, "input", "", "the path of the backup file to restore")
+	launchRestoreCmd.Flags().StringVar(&passphraseFileFlag, "passphrase-file", "", fmt.Sprintf("file holding the backup passphrase, %s is used when it is not set", launch.BackupPassphraseEnv))
+	launchRestoreCmd.MarkFlagRequired("input")
+
+	return launchRestoreCmd
+}
+
 // launchDown destroys a k3d cluster for Kubefirst console and API
 func launchDown() *cobra.Command {
 	launchDownCmd := &cobra.Command{
@@ -62,6 +175,84 @@ func launchDown() *cobra.Command {
 	return launchDownCmd
 }
 
+// launchProfile manages named launch up configurations stored in ~/.k1/profiles
+func launchProfile() *cobra.Command {
+	launchProfileCmd := &cobra.Command{
+		Use:              "profile",
+		Short:            "manage named launch up configurations",
+		Long:             "manage launch profiles, which store a chart version, database destination and helm values so the same console can be launched anywhere with kubefirst launch up --profile",
+		TraverseChildren: true,
+	}
+
+	launchProfileCmd.AddCommand(launchProfileCreate(), launchProfileList(), launchProfileShow(), launchProfileDelete())
+
+	return launchProfileCmd
+}
+
+// launchProfileCreate stores a launch profile
+func launchProfileCreate() *cobra.Command {
+	launchProfileCreateCmd := &cobra.Command{
+		Use:              "create <name>",
+		Short:            "create or replace a launch profile",
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.CreateProfile(args[0], chartVersionFlag, databaseFlag, valuesFilesFlag, additionalHelmFlags)
+		},
+	}
+
+	launchProfileCreateCmd.Flags().StringVar(&chartVersionFlag, "chart-version", "", "the console chart version, defaults to the version this kubefirst installs")
+	launchProfileCreateCmd.Flags().StringVar(&databaseFlag, "database", "", "the database destination, in-cluster, atlas or external - prompts during launch up when empty")
+	launchProfileCreateCmd.Flags().StringArrayVar(&valuesFilesFlag, "values", []string{}, "helm values file to store in the profile - can be used any number of times")
+	launchProfileCreateCmd.Flags().StringSliceVar(&additionalHelmFlags, "helm-flag", []string{}, "helm value to store in the profile, applied after --values - can be used any number of times")
+
+	return launchProfileCreateCmd
+}
+
+// launchProfileList lists the stored launch profiles
+func launchProfileList() *cobra.Command {
+	launchProfileListCmd := &cobra.Command{
+		Use:              "list",
+		Short:            "list launch profiles",
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.ListProfilesTable()
+		},
+	}
+
+	return launchProfileListCmd
+}
+
+// launchProfileShow prints a launch profile
+func launchProfileShow() *cobra.Command {
+	launchProfileShowCmd := &cobra.Command{
+		Use:              "show <name>",
+		Short:            "show a launch profile",
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.ShowProfile(args[0])
+		},
+	}
+
+	return launchProfileShowCmd
+}
+
+// launchProfileDelete removes a launch profile
+func launchProfileDelete() *cobra.Command {
+	launchProfileDeleteCmd := &cobra.Command{
+		Use:              "delete <name>",
+		Short:            "delete a launch profile",
+		Args:             cobra.ExactArgs(1),
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.RemoveProfile(args[0])
+		},
+	}
+
+	return launchProfileDeleteCmd
+}
+
 // launchCluster
 func launchCluster() *cobra.Command {
 	launchClusterCmd := &cobra.Command{
@@ -70,28 +261,58 @@ func launchCluster() *cobra.Command {
 		TraverseChildren: true,
 	}
 
-	launchClusterCmd.AddCommand(launchListClusters(), launchDeleteCluster())
+	launchClusterCmd.AddCommand(launchListClusters(), launchDescribeCluster(), launchDeleteCluster())
 
 	return launchClusterCmd
 }
 
 // launchListClusters makes a request to the console API to list created clusters
 func launchListClusters() *cobra.Command {
+	filter := launch.ClusterFilter{}
+
 	launchListClustersCmd := &cobra.Command{
 		Use:              "list",
 		Short:            "list clusters created by the kubefirst console",
 		TraverseChildren: true,
 		// PreRun:           common.CheckDocker,
 		Run: func(cmd *cobra.Command, args []string) {
-			launch.ListClusters()
+			launch.ListClusters(filter)
 		},
 	}
 
+	launchListClustersCmd.Flags().StringSliceVar(&filter.Statuses, "status", []string{}, "only list clusters with one of these statuses (e.g. provisioned,error)")
+	launchListClustersCmd.Flags().StringSliceVar(&filter.Providers, "provider", []string{}, "only list clusters on one of these cloud providers (e.g. aws,civo)")
+	launchListClustersCmd.Flags().StringSliceVar(&filter.Types, "type", []string{}, "only list clusters of one of these types (e.g. mgmt,workload)")
+	launchListClustersCmd.Flags().StringVar(&filter.SortBy, "sort-by", "created", "sort clusters by created or name")
+	launchListClustersCmd.Flags().BoolVar(&filter.Reverse, "reverse", false, "reverse the sort order")
+
 	return launchListClustersCmd
 }
 
+// launchDescribeCluster makes a request to the console API to describe a single cluster
+func launchDescribeCluster() *cobra.Command {
+	launchDescribeClusterCmd := &cobra.Command{
+		Use:              "describe",
+		Short:            "describe a cluster created by the kubefirst console",
+		TraverseChildren: true,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return fmt.Errorf("you must provide a cluster name as the only argument to this command")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			launch.DescribeCluster(args[0])
+		},
+	}
+
+	return launchDescribeClusterCmd
+}
+
 // launchDeleteCluster makes a request to the console API to delete a single cluster
 func launchDeleteCluster() *cobra.Command {
+	options := launch.DeleteOptions{}
+
 	launchDeleteClusterCmd := &cobra.Command{
 		Use:              "delete",
 		Short:            "delete a cluster created by the kubefirst console",
@@ -104,9 +325,13 @@ func launchDeleteCluster() *cobra.Command {
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			launch.DeleteCluster(args[0])
+			launch.DeleteCluster(args[0], options)
 		},
 	}
 
+	launchDeleteClusterCmd.Flags().BoolVar(&options.Yes, "yes", false, "delete the cluster without asking for confirmation")
+	launchDeleteClusterCmd.Flags().BoolVar(&options.Wait, "wait", true, "wait until the cluster is deleted")
+	launchDeleteClusterCmd.Flags().DurationVar(&options.Timeout, "timeout", launch.DefaultDeleteTimeout, "how long to wait for the cluster to be deleted")
+
 	return launchDeleteClusterCmd
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/kubefirst/kubefirst/cmd/civo"
 	"github.com/kubefirst/kubefirst/cmd/k3d"
 	"github.com/kubefirst/kubefirst/internal/common"
+	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/kubefirst/runtime/configs"
 
 	"github.com/kubefirst/runtime/pkg/progressPrinter"
@@ -55,14 +56,19 @@ func Execute() {
 func init() {
 	cobra.OnInitialize()
 	rootCmd.SilenceUsage = true
+	// the value is read by main before the command runs to select the progress backend
+	rootCmd.PersistentFlags().String("output", progress.OutputText, "output format, one of text, json or yaml")
 	rootCmd.AddCommand(
 		betaCmd,
 		aws.NewCommand(),
 		civo.NewCommand(),
+		ClusterCommand(),
+		ConsoleCommand(),
 		k3d.NewCommand(),
 		k3d.LocalCommandAlias(),
 		LaunchCommand(),
 		LetsEncryptCommand(),
 		TerraformCommand(),
+		ToolsCommand(),
 	)
 }
@@ -0,0 +1,144 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/bundle"
+	"github.com/kubefirst/kubefirst/internal/launch"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/tools"
+	"github.com/kubefirst/runtime/configs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleFileFlag                 string
+	bundleGitopsTemplateURLFlag    string
+	bundleGitopsTemplateBranchFlag string
+	manifestFileFlag               string
+)
+
+func ToolsCommand() *cobra.Command {
+	toolsCommand := &cobra.Command{
+		Use:   "tools",
+		Short: "manage the tools kubefirst downloads",
+		Long:  "manage the tools kubefirst downloads",
+	}
+
+	// wire up new commands
+	toolsCommand.AddCommand(toolsBundle(), toolsManifest())
+
+	return toolsCommand
+}
+
+// toolsBundle assembles every tool, the console helm chart, the kubernetes manifests
+// and the gitops template into one archive for installations without internet access
+func toolsBundle() *cobra.Command {
+	toolsBundleCmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "create an archive with everything launch up and k3d create download",
+		Long: `create an archive with every binary, the kubefirst helm chart, the argocd and vault
+manifests and the gitops template for this machine's os and architecture, along with a
+checksum manifest. pass it to launch up --bundle or k3d create --bundle to install without
+downloading them. container images are still pulled by the cluster and k3d create still
+needs to reach your git provider.`,
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			gitopsTemplateBranch := bundleGitopsTemplateBranchFlag
+			if gitopsTemplateBranch == "" {
+				gitopsTemplateBranch = configs.K1Version
+				if configs.K1Version == "development" {
+					gitopsTemplateBranch = "main"
+				}
+			}
+
+			progress.AddStep("Create bundle")
+
+			manifest, err := bundle.Create(bundle.Options{
+				Output: bundleFileFlag,
+				Chart:  launch.BundleChart(),
+				GitopsTemplate: bundle.GitopsTemplate{
+					URL:    bundleGitopsTemplateURLFlag,
+					Branch: gitopsTemplateBranch,
+				},
+			})
+			if err != nil {
+				progress.Error(fmt.Sprintf("error creating bundle: %s", err))
+				return
+			}
+
+			progress.CompleteStep("Create bundle")
+
+			bundled := make([]string, 0, len(manifest.Tools))
+			for name, version := range manifest.Tools {
+				bundled = append(bundled, fmt.Sprintf("%s %s", name, version))
+			}
+			sort.Strings(bundled)
+
+			message := "\n### :package: Bundle written to `" + bundleFileFlag + "`\n\n"
+			message += fmt.Sprintf("%s/%s with %d checksummed files\n\n", manifest.OS, manifest.Arch, len(manifest.Checksums))
+			for _, tool := range bundled {
+				message += fmt.Sprintf("- %s\n", tool)
+			}
+			message += fmt.Sprintf("- chart %s %s\n", manifest.Chart.Name, manifest.Chart.Version)
+			message += fmt.Sprintf("- gitops template %s at %s\n", manifest.GitopsTemplate.URL, manifest.GitopsTemplate.Branch)
+			progress.Success(message)
+		},
+	}
+
+	toolsBundleCmd.Flags().StringVar(&bundleFileFlag, "file", "kubefirst-bundle.tar.gz", "the path of the bundle archive to write")
+	toolsBundleCmd.Flags().StringVar(&bundleGitopsTemplateURLFlag, "gitops-template-url", "https://github.com/kubefirst/gitops-template.git", "the fully qualified url to the gitops-template repository to bundle")
+	toolsBundleCmd.Flags().StringVar(&bundleGitopsTemplateBranchFlag, "gitops-template-branch", "", "the branch or tag of the gitops-template repository to bundle, defaults to the one k3d create uses")
+
+	return toolsBundleCmd
+}
+
+// toolsManifest downloads every pinned tool for every supported platform and writes the
+// checksum of each binary in the format of the embedded tool manifest
+func toolsManifest() *cobra.Command {
+	toolsManifestCmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "generate the tool manifest with the checksum of every tool for every platform",
+		Long: `download every tool at its pinned version for every supported platform, verify it
+against the checksum its project publishes where there is one, and write the sha256 of
+each binary. run it with --file internal/tools/manifest.json after changing a tool version.`,
+		TraverseChildren: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			progress.AddStep("Generate tool manifest")
+
+			manifest, err := tools.GenerateManifest()
+			if err != nil {
+				progress.Error(fmt.Sprintf("error generating tool manifest: %s", err))
+				return
+			}
+
+			content, err := json.MarshalIndent(manifest, "", "  ")
+			if err != nil {
+				progress.Error(err.Error())
+				return
+			}
+			err = os.WriteFile(manifestFileFlag, append(content, '\n'), 0644)
+			if err != nil {
+				progress.Error(fmt.Sprintf("error writing %s: %s", manifestFileFlag, err))
+				return
+			}
+
+			progress.CompleteStep("Generate tool manifest")
+			progress.Success(fmt.Sprintf("\n### :lock: Tool manifest written to `%s`\n\n%d tools pinned for %s\n", manifestFileFlag, len(manifest.Tools), strings.Join(tools.Platforms, ", ")))
+		},
+	}
+
+	toolsManifestCmd.Flags().StringVar(&manifestFileFlag, "file", "tools-manifest.json", "the path of the tool manifest to write")
+
+	return toolsManifestCmd
+}
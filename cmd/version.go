@@ -23,6 +23,16 @@ var versionCmd = &cobra.Command{
 	Short: "print the version number for kubefirst-cli",
 	Long:  `All software has versions. This is kubefirst's`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if progress.IsStructuredOutput() {
+			err := progress.Document(map[string]string{
+				"version": configs.K1Version,
+			})
+			if err != nil {
+				progress.Error(err.Error())
+			}
+			return
+		}
+
 		versionMsg := `
 ##
 ### kubefirst-cli golang utility version:` + fmt.Sprintf("`%s`", configs.K1Version)
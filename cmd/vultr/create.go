@@ -7,6 +7,7 @@ See the LICENSE file for more details.
 package vultr
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -16,7 +17,6 @@ import (
 	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/kubefirst/kubefirst/internal/provision"
 	"github.com/kubefirst/kubefirst/internal/utilities"
-	"github.com/kubefirst/runtime/pkg"
 	internalssh "github.com/kubefirst/runtime/pkg/ssh"
 	"github.com/rs/zerolog/log"
 
@@ -31,7 +31,7 @@ func createVultr(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	progress.DisplayLogHints(15)
+	progress.DisplayLogHints(progress.EstimatedMinutes("vultr", 15))
 
 	err = ValidateProvidedFlags(cliFlags.GitProvider)
 	if err != nil {
@@ -49,7 +49,7 @@ func createVultr(cmd *cobra.Command, args []string) error {
 
 	utilities.CreateK1ClusterDirectory(clusterNameFlag)
 
-	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags.GitProvider, cliFlags.GithubOrg, cliFlags.GitlabGroup)
+	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags)
 
 	if err != nil {
 		progress.Error(err.Error())
@@ -59,20 +59,18 @@ func createVultr(cmd *cobra.Command, args []string) error {
 	// Validate git
 	executionControl := viper.GetBool(fmt.Sprintf("kubefirst-checks.%s-credentials", cliFlags.GitProvider))
 	if !executionControl {
-		newRepositoryNames := []string{"gitops", "metaphor"}
-		newTeamNames := []string{"admins", "developers"}
-
+		// the kubefirst api only creates the default repository and team names
 		initGitParameters := gitShim.GitInitParameters{
 			GitProvider:  cliFlags.GitProvider,
 			GitToken:     gitAuth.Token,
 			GitOwner:     gitAuth.Owner,
-			Repositories: newRepositoryNames,
-			Teams:        newTeamNames,
+			Repositories: utilities.DefaultGitNames.Repositories(),
+			Teams:        utilities.DefaultGitNames.Teams(),
 		}
 
 		err = gitShim.InitializeGitProvider(&initGitParameters)
 		if err != nil {
-			progress.Error(err.Error())
+			gitShim.DisplayInitError(err)
 			return nil
 		}
 	}
@@ -81,12 +79,19 @@ func createVultr(cmd *cobra.Command, args []string) error {
 
 	k3dClusterCreationComplete := viper.GetBool("launch.deployed")
 	if !k3dClusterCreationComplete {
-		launch.Up(nil, true, cliFlags.UseTelemetry)
+		launch.Up(launch.UpOptions{InCluster: true, UseTelemetry: cliFlags.UseTelemetry})
 	}
 
-	err = pkg.IsAppAvailable(fmt.Sprintf("%s/api/proxyHealth", cluster.GetConsoleIngresUrl()), "kubefirst api")
+	consoleClient, err := cluster.NewDefaultConsoleClient()
 	if err != nil {
-		progress.Error("unable to start kubefirst api")
+		progress.Error(err.Error())
+		return nil
+	}
+
+	err = consoleClient.WaitForHealthy(context.Background(), 60)
+	if err != nil {
+		progress.Error(fmt.Sprintf("unable to start kubefirst api: %s", err))
+		return nil
 	}
 
 	provision.CreateMgmtCluster(gitAuth, cliFlags)
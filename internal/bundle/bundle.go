@@ -0,0 +1,205 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kubefirst/kubefirst/internal/tools"
+	"github.com/kubefirst/runtime/pkg"
+	"github.com/kubefirst/runtime/pkg/k3d"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ManifestFile describes the bundle and holds the checksum of every other file in it
+	ManifestFile = "manifest.json"
+
+	ArgoCDManifest       = "argocd-k3d.yaml"
+	VaultHandlerManifest = "vault-handler.yaml"
+
+	toolsDir          = "tools"
+	chartsDir         = "charts"
+	manifestsDir      = "manifests"
+	gitopsTemplateDir = "repositories/gitops-template"
+)
+
+// Kustomizations maps the manifests rendered into a bundle to the kustomization they are built from
+var Kustomizations = map[string]string{
+	ArgoCDManifest:       fmt.Sprintf("github.com:kubefirst/manifests/argocd/k3d?ref=%s", pkg.KubefirstManifestRepoRef),
+	VaultHandlerManifest: "github.com:kubefirst/manifests.git/vault-handler/replicas-1",
+}
+
+// Chart is the helm chart installed by launch
+type Chart struct {
+	Name    string `json:"name"`
+	RepoURL string `json:"repo_url"`
+	Version string `json:"version"`
+}
+
+// GitopsTemplate is the repository a k3d installation generates the gitops repository from
+type GitopsTemplate struct {
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+}
+
+// Manifest describes the content of a bundle
+type Manifest struct {
+	KubefirstVersion string            `json:"kubefirst_version"`
+	OS               string            `json:"os"`
+	Arch             string            `json:"arch"`
+	Tools            map[string]string `json:"tools"`
+	Chart            Chart             `json:"chart"`
+	GitopsTemplate   GitopsTemplate    `json:"gitops_template"`
+	// Checksums maps the path of every file in the bundle to its sha256
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Bundle is an extracted and verified bundle
+type Bundle struct {
+	Dir      string
+	Manifest Manifest
+}
+
+// Open extracts the bundle archive to a temporary directory and verifies its content
+// against the checksum manifest, Close removes the directory
+func Open(archivePath string) (*Bundle, error) {
+	dir, err := os.MkdirTemp("", "kubefirst-bundle-")
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{Dir: dir}
+	err = b.open(archivePath)
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("error opening bundle %s: %s", archivePath, err)
+	}
+
+	log.Info().Msgf("opened bundle %s for kubefirst %s", archivePath, b.Manifest.KubefirstVersion)
+
+	return b, nil
+}
+
+func (b *Bundle) open(archivePath string) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	err = extract(archive, b.Dir)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(filepath.Join(b.Dir, ManifestFile))
+	if err != nil {
+		return fmt.Errorf("bundle is missing %s: %s", ManifestFile, err)
+	}
+	err = json.Unmarshal(content, &b.Manifest)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %s", ManifestFile, err)
+	}
+
+	if b.Manifest.OS != k3d.LocalhostOS || b.Manifest.Arch != k3d.LocalhostARCH {
+		return fmt.Errorf("bundle was built for %s/%s but this machine is %s/%s", b.Manifest.OS, b.Manifest.Arch, k3d.LocalhostOS, k3d.LocalhostARCH)
+	}
+
+	for path, expected := range b.Manifest.Checksums {
+		actual, err := checksum(filepath.Join(b.Dir, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+		if actual != expected {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, expected, actual)
+		}
+	}
+
+	return nil
+}
+
+// Close removes the extracted bundle
+func (b *Bundle) Close() {
+	err := os.RemoveAll(b.Dir)
+	if err != nil {
+		log.Warn().Msgf("could not remove extracted bundle %s: %s", b.Dir, err)
+	}
+}
+
+// InstallTool copies a tool from the bundle into destinationDir
+func (b *Bundle) InstallTool(tool tools.Tool, destinationDir string) error {
+	version, exists := b.Manifest.Tools[tool.Name]
+	if !exists {
+		return fmt.Errorf("bundle does not contain %s", tool.Name)
+	}
+	if version != tool.Version {
+		return fmt.Errorf("bundle contains %s %s but this kubefirst pins %s - create a new bundle with `kubefirst tools bundle`", tool.Name, version, tool.Version)
+	}
+
+	err := os.MkdirAll(destinationDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	source, err := os.Open(tool.Path(filepath.Join(b.Dir, toolsDir)))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.OpenFile(tool.Path(destinationDir), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(destination, source)
+	destination.Close()
+	if err != nil {
+		return fmt.Errorf("error installing %s from bundle: %s", tool.Name, err)
+	}
+
+	err = tool.Register(destinationDir)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("installed %s %s from bundle", tool.Name, version)
+
+	return nil
+}
+
+// ChartPath returns the packaged helm chart in the bundle
+func (b *Bundle) ChartPath() string {
+	return filepath.Join(b.Dir, chartsDir, chartFile(b.Manifest.Chart))
+}
+
+// ReadManifest returns a rendered kubernetes manifest from the bundle
+func (b *Bundle) ReadManifest(name string) (*bytes.Buffer, error) {
+	content, err := os.ReadFile(filepath.Join(b.Dir, manifestsDir, name))
+	if err != nil {
+		return nil, fmt.Errorf("bundle does not contain manifest %s: %s", name, err)
+	}
+
+	return bytes.NewBuffer(content), nil
+}
+
+// GitopsTemplatePath returns the clone of the gitops template in the bundle, it can be
+// used as the gitops template url
+func (b *Bundle) GitopsTemplatePath() string {
+	return filepath.Join(b.Dir, gitopsTemplateDir)
+}
+
+// chartFile is the name helm gives a packaged chart
+func chartFile(chart Chart) string {
+	return fmt.Sprintf("%s-%s.tgz", chart.Name, chart.Version)
+}
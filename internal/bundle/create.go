@@ -0,0 +1,112 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/tools"
+	"github.com/kubefirst/runtime/configs"
+	"github.com/kubefirst/runtime/pkg/gitClient"
+	"github.com/kubefirst/runtime/pkg/k3d"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/rs/zerolog/log"
+)
+
+// Options describe what goes into a bundle
+type Options struct {
+	// Output is the path of the archive to write
+	Output         string
+	Chart          Chart
+	GitopsTemplate GitopsTemplate
+}
+
+// Create downloads every tool, the helm chart, the kubernetes manifests and the gitops
+// template for the local os and architecture and writes them to a gzipped tar archive
+// along with a checksum manifest
+func Create(options Options) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "kubefirst-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest := &Manifest{
+		KubefirstVersion: configs.K1Version,
+		OS:               k3d.LocalhostOS,
+		Arch:             k3d.LocalhostARCH,
+		Tools:            map[string]string{},
+		Chart:            options.Chart,
+		GitopsTemplate:   options.GitopsTemplate,
+	}
+
+	stagingToolsDir := filepath.Join(staging, toolsDir)
+	for _, tool := range tools.All {
+		err = tool.Download(stagingToolsDir)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Tools[tool.Name] = tool.Version
+	}
+
+	log.Info().Msgf("pulling helm chart %s %s", options.Chart.Name, options.Chart.Version)
+	err = helm.NewClient("").Pull(helm.Chart{
+		Name:    options.Chart.Name,
+		RepoURL: options.Chart.RepoURL,
+		Version: options.Chart.Version,
+	}, filepath.Join(staging, chartsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(filepath.Join(staging, manifestsDir), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	kcfg := k8s.KubernetesClient{}
+	for name, kustomization := range Kustomizations {
+		log.Info().Msgf("rendering %s", kustomization)
+		yamlData, err := kcfg.KustomizeBuild(kustomization)
+		if err != nil {
+			return nil, fmt.Errorf("error rendering %s: %s", kustomization, err)
+		}
+		err = os.WriteFile(filepath.Join(staging, manifestsDir, name), yamlData.Bytes(), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = gitClient.Clone(options.GitopsTemplate.Branch, filepath.Join(staging, gitopsTemplateDir), options.GitopsTemplate.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error cloning gitops template %s at %s: %s", options.GitopsTemplate.URL, options.GitopsTemplate.Branch, err)
+	}
+
+	manifest.Checksums, err = checksums(staging)
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(filepath.Join(staging, ManifestFile), content, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = compress(staging, options.Output)
+	if err != nil {
+		return nil, fmt.Errorf("error writing bundle %s: %s", options.Output, err)
+	}
+	log.Info().Msgf("wrote bundle %s", options.Output)
+
+	return manifest, nil
+}
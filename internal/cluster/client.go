@@ -0,0 +1,209 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// defaultRequestTimeout bounds a single attempt against the console API
+	defaultRequestTimeout = time.Second * 30
+	// defaultMaxRetries is the number of additional attempts made for retryable failures
+	defaultMaxRetries = 3
+	// defaultRetryBackoff is the initial wait between attempts, doubled after each retry
+	defaultRetryBackoff = time.Millisecond * 500
+)
+
+// ConsoleClient talks to the kubefirst API through the console's /api/proxy endpoint
+type ConsoleClient struct {
+	// BaseURL is the console address, e.g. https://console.kubefirst.dev
+	BaseURL string
+	// HTTPClient is used for every request
+	HTTPClient *http.Client
+	// Timeout bounds each individual attempt
+	Timeout time.Duration
+	// MaxRetries is the number of retries of GET and DELETE requests for connection errors
+	// and 5xx responses
+	MaxRetries int
+	// RetryBackoff is the initial backoff between retries
+	RetryBackoff time.Duration
+	// Token is sent as a bearer token when set
+	Token string
+}
+
+// NewConsoleClient returns a ConsoleClient with default timeouts and retries
+func NewConsoleClient(baseURL string) *ConsoleClient {
+	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+
+	return &ConsoleClient{
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:   &http.Client{Transport: customTransport},
+		Timeout:      defaultRequestTimeout,
+		MaxRetries:   defaultMaxRetries,
+		RetryBackoff: defaultRetryBackoff,
+	}
+}
+
+// NewConsoleClientForContext returns a ConsoleClient configured for a console context
+func NewConsoleClientForContext(consoleContext ConsoleContext) (*ConsoleClient, error) {
+	customTransport, err := consoleContext.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewConsoleClient(consoleContext.URL)
+	c.HTTPClient = &http.Client{Transport: customTransport}
+	c.Token = consoleContext.Token
+
+	return c, nil
+}
+
+// proxyURL returns the /api/proxy address, optionally targeting an API path via the url query parameter
+func (c *ConsoleClient) proxyURL(apiPath string) string {
+	if apiPath == "" {
+		return fmt.Sprintf("%s/api/proxy", c.BaseURL)
+	}
+
+	return fmt.Sprintf("%s/api/proxy?url=%s", c.BaseURL, url.QueryEscape(apiPath))
+}
+
+// do sends a request to the console API, retrying connection errors and 5xx responses of
+// idempotent requests with exponential backoff, and decodes a successful response body into
+// out when provided. POST is never retried, a create that timed out on the server side could
+// otherwise be submitted twice
+func (c *ConsoleClient) do(ctx context.Context, method string, target string, payload interface{}, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := c.RetryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Info().Msgf("retrying %s %s in %s (attempt %d of %d): %s", method, target, backoff, attempt, c.MaxRetries, lastErr)
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = backoff * 2
+		}
+
+		resBody, err := c.attempt(ctx, method, target, body)
+		if err == nil {
+			if out == nil || len(resBody) == 0 {
+				return nil
+			}
+
+			err = json.Unmarshal(resBody, out)
+			if err != nil {
+				return fmt.Errorf("unable to decode response from %s %s: %w", method, target, err)
+			}
+
+			return nil
+		}
+
+		lastErr = err
+		if !isIdempotent(method) || !isRetryable(ctx, err) {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+// attempt performs a single request bounded by the client timeout
+func (c *ConsoleClient) attempt(ctx context.Context, method string, target string, body []byte) ([]byte, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return nil, &APIError{
+			Method:     method,
+			URL:        target,
+			StatusCode: res.StatusCode,
+			Body:       strings.TrimSpace(string(resBody)),
+		}
+	}
+
+	return resBody, nil
+}
+
+// isIdempotent reports whether a request can be sent again without side effects
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodDelete
+}
+
+// isRetryable reports whether a failed attempt should be tried again
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if errors.Is(err, ErrServerError) {
+		return true
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
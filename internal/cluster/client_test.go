@@ -0,0 +1,264 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiTypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/kubefirst/internal/types"
+)
+
+// proxyStandIn answers /api/proxy with the responses in order, the last one is repeated
+type proxyStandIn struct {
+	responses []proxyResponse
+	requests  int32
+	// lastURL is the api path of the last request, from the url query or the request body
+	lastURL string
+}
+
+type proxyResponse struct {
+	status int
+	body   string
+	delay  time.Duration
+}
+
+func (p *proxyStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/proxy" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	p.lastURL = r.URL.Query().Get("url")
+	if r.Method == http.MethodPost {
+		request := types.ProxyCreateClusterRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		p.lastURL = request.Url
+	}
+
+	i := int(atomic.AddInt32(&p.requests, 1)) - 1
+	if i >= len(p.responses) {
+		i = len(p.responses) - 1
+	}
+	response := p.responses[i]
+
+	if response.delay > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(response.delay):
+		}
+	}
+	w.WriteHeader(response.status)
+	w.Write([]byte(response.body))
+}
+
+func newTestClient(url string) *ConsoleClient {
+	c := NewConsoleClient(url)
+	c.RetryBackoff = time.Millisecond
+	c.Timeout = time.Second
+
+	return c
+}
+
+func TestGetCluster(t *testing.T) {
+	tests := []struct {
+		name         string
+		responses    []proxyResponse
+		wantErr      error
+		wantRequests int32
+		wantCluster  string
+	}{
+		{
+			name:         "success",
+			responses:    []proxyResponse{{status: http.StatusOK, body: `{"cluster_name":"kubefirst","status":"provisioned"}`}},
+			wantRequests: 1,
+			wantCluster:  "kubefirst",
+		},
+		{
+			name:         "not found",
+			responses:    []proxyResponse{{status: http.StatusNotFound, body: `{"error":"cluster not found"}`}},
+			wantErr:      ErrNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:         "conflict",
+			responses:    []proxyResponse{{status: http.StatusConflict}},
+			wantErr:      ErrConflict,
+			wantRequests: 1,
+		},
+		{
+			name:         "unauthorized",
+			responses:    []proxyResponse{{status: http.StatusUnauthorized}},
+			wantErr:      ErrUnauthorized,
+			wantRequests: 1,
+		},
+		{
+			name: "server error then success",
+			responses: []proxyResponse{
+				{status: http.StatusBadGateway},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusOK, body: `{"cluster_name":"kubefirst"}`},
+			},
+			wantRequests: 3,
+			wantCluster:  "kubefirst",
+		},
+		{
+			name:         "server error exhausts retries",
+			responses:    []proxyResponse{{status: http.StatusServiceUnavailable, body: "unavailable"}},
+			wantErr:      ErrServerError,
+			wantRequests: defaultMaxRetries + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &proxyStandIn{responses: tt.responses}
+			server := httptest.NewServer(standIn)
+			defer server.Close()
+
+			cluster, err := newTestClient(server.URL).GetCluster(context.Background(), "kubefirst")
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("GetCluster() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&standIn.requests); got != tt.wantRequests {
+				t.Errorf("GetCluster() sent %d requests, want %d", got, tt.wantRequests)
+			}
+			if cluster.ClusterName != tt.wantCluster {
+				t.Errorf("GetCluster() cluster name = %q, want %q", cluster.ClusterName, tt.wantCluster)
+			}
+			if standIn.lastURL != "/cluster/kubefirst" {
+				t.Errorf("GetCluster() requested %q, want /cluster/kubefirst", standIn.lastURL)
+			}
+		})
+	}
+}
+
+func TestNotFoundCarriesBody(t *testing.T) {
+	server := httptest.NewServer(&proxyStandIn{responses: []proxyResponse{{status: http.StatusNotFound, body: "cluster not found"}}})
+	defer server.Close()
+
+	err := newTestClient(server.URL).DeleteCluster(context.Background(), "kubefirst")
+	if !IsNotFound(err) {
+		t.Fatalf("IsNotFound(%v) = false, want true", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Body != "cluster not found" {
+		t.Errorf("DeleteCluster() error = %v, want an APIError with the response body", err)
+	}
+}
+
+func TestCreateClusterIsNotRetried(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []proxyResponse
+		wantErr   error
+	}{
+		{
+			name:      "success",
+			responses: []proxyResponse{{status: http.StatusAccepted}},
+		},
+		{
+			name:      "bad request",
+			responses: []proxyResponse{{status: http.StatusBadRequest, body: "invalid definition"}},
+			wantErr:   &APIError{},
+		},
+		{
+			name:      "server error",
+			responses: []proxyResponse{{status: http.StatusInternalServerError}, {status: http.StatusAccepted}},
+			wantErr:   ErrServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &proxyStandIn{responses: tt.responses}
+			server := httptest.NewServer(standIn)
+			defer server.Close()
+
+			definition := apiTypes.ClusterDefinition{ClusterName: "kubefirst"}
+			err := newTestClient(server.URL).CreateCluster(context.Background(), definition)
+
+			var apiErr *APIError
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("CreateCluster() error = %v, want nil", err)
+				}
+			case *APIError:
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("CreateCluster() error = %v, want an APIError", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("CreateCluster() error = %v, want %v", err, want)
+				}
+			}
+			if got := atomic.LoadInt32(&standIn.requests); got != 1 {
+				t.Errorf("CreateCluster() sent %d requests, want 1", got)
+			}
+			if standIn.lastURL != "/cluster/kubefirst" {
+				t.Errorf("CreateCluster() proxied %q, want /cluster/kubefirst", standIn.lastURL)
+			}
+		})
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name string
+		// timeout bounds each attempt, contextTimeout the whole call
+		timeout        time.Duration
+		contextTimeout time.Duration
+		wantErr        error
+		wantRequests   int32
+	}{
+		{
+			name:           "context deadline stops retries",
+			timeout:        time.Second,
+			contextTimeout: 50 * time.Millisecond,
+			wantErr:        context.DeadlineExceeded,
+			wantRequests:   1,
+		},
+		{
+			name:           "attempt timeout is retried",
+			timeout:        20 * time.Millisecond,
+			contextTimeout: 5 * time.Second,
+			wantErr:        context.DeadlineExceeded,
+			wantRequests:   defaultMaxRetries + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn := &proxyStandIn{responses: []proxyResponse{{status: http.StatusOK, body: "[]", delay: time.Second}}}
+			server := httptest.NewServer(standIn)
+			defer server.Close()
+
+			c := newTestClient(server.URL)
+			c.Timeout = tt.timeout
+			ctx, cancel := context.WithTimeout(context.Background(), tt.contextTimeout)
+			defer cancel()
+
+			_, err := c.GetClusters(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetClusters() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&standIn.requests); got != tt.wantRequests {
+				t.Errorf("GetClusters() sent %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
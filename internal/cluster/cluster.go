@@ -7,13 +7,10 @@ See the LICENSE file for more details.
 package cluster
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -21,209 +18,135 @@ import (
 	"github.com/kubefirst/kubefirst/internal/types"
 )
 
+// GetConsoleIngresUrl returns the address of the active console context
 func GetConsoleIngresUrl() string {
-
-	if strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true" { //allow using local console running on port 3000
-		return "http://localhost:3000"
+	consoleContext, err := GetCurrentConsoleContext()
+	if err != nil {
+		log.Warn().Msgf("%s - falling back to the %s console context", err, DefaultConsoleContextName)
+		return defaultConsoleContext().URL
 	}
 
-	return "https://console.kubefirst.dev"
+	return consoleContext.URL
 }
 
-func CreateCluster(cluster apiTypes.ClusterDefinition) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
-	requestObject := types.ProxyCreateClusterRequest{
-		Body: cluster,
-		Url:  fmt.Sprintf("/cluster/%s", cluster.ClusterName),
-	}
-
-	payload, err := json.Marshal(requestObject)
+// NewDefaultConsoleClient returns a ConsoleClient for the active console context
+func NewDefaultConsoleClient() (*ConsoleClient, error) {
+	consoleContext, err := GetCurrentConsoleContext()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/proxy", GetConsoleIngresUrl()), bytes.NewReader(payload))
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
+	return NewConsoleClientForContext(consoleContext)
+}
 
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
-	}
+// Health checks the console health endpoint once
+func (c *ConsoleClient) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, fmt.Sprintf("%s/api/proxyHealth", c.BaseURL), nil, nil)
+}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Info().Msgf("unable to create cluster %s", err)
+// WaitForHealthy polls the console health endpoint until it responds or attempts are exhausted
+func (c *ConsoleClient) WaitForHealthy(ctx context.Context, attempts int) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = c.Health(ctx)
+		if err == nil {
+			log.Info().Msg("kubefirst api is up and running")
+			return nil
+		}
+
+		log.Info().Msgf("waiting for kubefirst api to be ready (%d/%d): %s", i+1, attempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * 5):
+		}
+	}
+
+	return fmt.Errorf("kubefirst api at %s is not available: %w", c.BaseURL, err)
+}
 
-		return err
+// CreateCluster submits a cluster definition to the kubefirst API
+func (c *ConsoleClient) CreateCluster(ctx context.Context, cluster apiTypes.ClusterDefinition) error {
+	requestObject := types.ProxyCreateClusterRequest{
+		Body: cluster,
+		Url:  fmt.Sprintf("/cluster/%s", cluster.ClusterName),
 	}
 
-	if res.StatusCode != http.StatusOK {
-		log.Info().Msgf("unable to create cluster %s %s", res.Status, body)
-		return fmt.Errorf("unable to create cluster %s %s", res.Status, body)
+	err := c.do(ctx, http.MethodPost, c.proxyURL(""), requestObject, nil)
+	if err != nil {
+		return fmt.Errorf("unable to create cluster %s: %w", cluster.ClusterName, err)
 	}
 
-	log.Info().Msgf("Created cluster: %s", string(body))
+	log.Info().Msgf("Created cluster: %s", cluster.ClusterName)
 
 	return nil
 }
 
-func ResetClusterProgress(clusterName string) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
+// ResetClusterProgress clears the provisioning state of a cluster so it can be created again
+func (c *ConsoleClient) ResetClusterProgress(ctx context.Context, clusterName string) error {
 	requestObject := types.ProxyResetClusterRequest{
 		Url: fmt.Sprintf("/cluster/%s/reset_progress", clusterName),
 	}
 
-	payload, err := json.Marshal(requestObject)
-	if err != nil {
-		return err
-	}
-
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/proxy", GetConsoleIngresUrl()), bytes.NewReader(payload))
+	err := c.do(ctx, http.MethodPost, c.proxyURL(""), requestObject, nil)
 	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
+		return fmt.Errorf("unable to reset progress for cluster %s: %w", clusterName, err)
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
-	}
-
-	if res.StatusCode != http.StatusOK {
-		log.Info().Msgf("unable to create cluster %s", res.Status)
-		return err
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Info().Msgf("unable to create cluster %s", err)
-
-		return err
-	}
-
-	log.Info().Msgf("Import: %s", string(body))
+	log.Info().Msgf("Reset progress for cluster: %s", clusterName)
 
 	return nil
 }
 
-func GetCluster(clusterName string) (apiTypes.Cluster, error) {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
+// GetCluster returns a single cluster record
+func (c *ConsoleClient) GetCluster(ctx context.Context, clusterName string) (apiTypes.Cluster, error) {
 	cluster := apiTypes.Cluster{}
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/proxy?url=/cluster/%s", GetConsoleIngresUrl(), clusterName), nil)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return cluster, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return cluster, err
-	}
-
-	if res.StatusCode != http.StatusOK {
-		log.Info().Msgf("unable to get cluster %s, continuing", res.Status)
-		return cluster, err
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Info().Msgf("unable to get cluster %s", err)
-		return cluster, err
-	}
 
-	err = json.Unmarshal(body, &cluster)
+	err := c.do(ctx, http.MethodGet, c.proxyURL(fmt.Sprintf("/cluster/%s", clusterName)), nil, &cluster)
 	if err != nil {
-		log.Info().Msgf("unable to cast cluster object %s", err)
-		return cluster, err
+		return cluster, fmt.Errorf("unable to get cluster %s: %w", clusterName, err)
 	}
 
 	return cluster, nil
 }
 
-func GetClusters() ([]apiTypes.Cluster, error) {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
+// GetClusters returns every cluster record known to the kubefirst API
+func (c *ConsoleClient) GetClusters(ctx context.Context) ([]apiTypes.Cluster, error) {
 	clusters := []apiTypes.Cluster{}
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/proxy?url=/cluster", GetConsoleIngresUrl()), nil)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return clusters, err
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	res, err := httpClient.Do(req)
+	err := c.do(ctx, http.MethodGet, c.proxyURL("/cluster"), nil, &clusters)
 	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return clusters, err
-	}
-
-	if res.StatusCode != http.StatusOK {
-		log.Info().Msgf("unable to get clusters %s, continuing", res.Status)
-		return clusters, err
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Info().Msgf("unable to get clusters %s", err)
-		return clusters, err
-	}
-
-	err = json.Unmarshal(body, &clusters)
-	if err != nil {
-		log.Info().Msgf("unable to cast clusters object %s", err)
-		return clusters, err
+		return clusters, fmt.Errorf("unable to get clusters: %w", err)
 	}
 
 	return clusters, nil
 }
 
-func DeleteCluster(clusterName string) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
-	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api/proxy?url=/cluster/%s", GetConsoleIngresUrl(), clusterName), nil)
+// DeleteCluster requests the deletion of a single cluster
+func (c *ConsoleClient) DeleteCluster(ctx context.Context, clusterName string) error {
+	err := c.do(ctx, http.MethodDelete, c.proxyURL(fmt.Sprintf("/cluster/%s", clusterName)), nil, nil)
 	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
+		return fmt.Errorf("unable to delete cluster %s: %w", clusterName, err)
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Info().Msgf("error %s", err)
-		return err
-	}
-
-	if res.StatusCode != http.StatusOK {
-		log.Info().Msgf("unable to delete cluster %s, continuing", res.Status)
-		return err
-	}
+	return nil
+}
 
-	_, err = io.ReadAll(res.Body)
-	if err != nil {
-		log.Info().Msgf("unable to delete cluster %s", err)
-		return err
+// WatchError returns why a watched cluster will never be provisioned, it is nil while the
+// cluster can still get there. a cluster that is deleted, failed or stopped provisioning
+// on an error without failing is done changing
+func WatchError(cluster apiTypes.Cluster) error {
+	switch cluster.Status {
+	case "provisioned":
+		return nil
+	case "deleting", "deleted":
+		return fmt.Errorf("cluster %s is %s and will not be provisioned", cluster.ClusterName, cluster.Status)
+	case "error":
+		return fmt.Errorf("cluster %s failed to provision: %s", cluster.ClusterName, cluster.LastCondition)
+	}
+
+	if !cluster.InProgress && cluster.LastCondition != "" {
+		return fmt.Errorf("cluster %s stopped provisioning: %s", cluster.ClusterName, cluster.LastCondition)
 	}
 
 	return nil
@@ -0,0 +1,69 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"testing"
+
+	apiTypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+func TestWatchError(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster apiTypes.Cluster
+		wantErr bool
+	}{
+		{
+			name:    "provisioning",
+			cluster: apiTypes.Cluster{Status: "provisioning", InProgress: true},
+		},
+		{
+			name:    "not started yet",
+			cluster: apiTypes.Cluster{Status: "provisioning"},
+		},
+		{
+			name:    "provisioned",
+			cluster: apiTypes.Cluster{Status: "provisioned", LastCondition: "an earlier error"},
+		},
+		{
+			name:    "failed",
+			cluster: apiTypes.Cluster{Status: "error", LastCondition: "terraform apply failed"},
+			wantErr: true,
+		},
+		{
+			name:    "deleting",
+			cluster: apiTypes.Cluster{Status: "deleting", InProgress: true},
+			wantErr: true,
+		},
+		{
+			name:    "deleted",
+			cluster: apiTypes.Cluster{Status: "deleted"},
+			wantErr: true,
+		},
+		{
+			name:    "stopped on an error",
+			cluster: apiTypes.Cluster{Status: "provisioning", LastCondition: "unable to reach the git provider"},
+			wantErr: true,
+		},
+		{
+			name:    "error while still in progress",
+			cluster: apiTypes.Cluster{Status: "provisioning", InProgress: true, LastCondition: "retrying"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cluster.ClusterName = "kubefirst"
+
+			err := WatchError(tt.cluster)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WatchError() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultConsoleURL is the address of the console deployed by `kubefirst launch up`
+	DefaultConsoleURL = "https://console.kubefirst.dev"
+	// LocalDebugConsoleURL is used when K1_LOCAL_DEBUG=true to reach a console running on port 3000
+	LocalDebugConsoleURL = "http://localhost:3000"
+	// DefaultConsoleContextName is the built-in context that targets DefaultConsoleURL
+	DefaultConsoleContextName = "default"
+
+	consoleContextsKey       = "console.contexts"
+	consoleCurrentContextKey = "console.current-context"
+)
+
+// consoleContextNamePattern matches the names a context can be stored under, a '.' would
+// nest the context inside another key of the kubefirst config
+var consoleContextNamePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// ConsoleContext describes a console endpoint, the same way a kubeconfig context describes a cluster
+type ConsoleContext struct {
+	Name     string `mapstructure:"-" json:"name" yaml:"name"`
+	URL      string `mapstructure:"url" json:"url" yaml:"url"`
+	CABundle string `mapstructure:"ca-bundle" json:"ca_bundle,omitempty" yaml:"ca_bundle,omitempty"`
+	Insecure bool   `mapstructure:"insecure" json:"insecure" yaml:"insecure"`
+	Token    string `mapstructure:"token" json:"-" yaml:"-"`
+}
+
+// defaultConsoleContext returns the built-in context, honoring K1_LOCAL_DEBUG
+func defaultConsoleContext() ConsoleContext {
+	consoleURL := DefaultConsoleURL
+	if strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true" { //allow using local console running on port 3000
+		consoleURL = LocalDebugConsoleURL
+	}
+
+	return ConsoleContext{
+		Name: DefaultConsoleContextName,
+		URL:  consoleURL,
+	}
+}
+
+// GetConsoleContexts returns the built-in context followed by every context stored in the kubefirst config
+func GetConsoleContexts() ([]ConsoleContext, error) {
+	stored := map[string]ConsoleContext{}
+	err := viper.UnmarshalKey(consoleContextsKey, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read console contexts from kubefirst config: %s", err)
+	}
+
+	names := make([]string, 0, len(stored))
+	for name := range stored {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	contexts := []ConsoleContext{defaultConsoleContext()}
+	for _, name := range names {
+		consoleContext := stored[name]
+		consoleContext.Name = name
+		contexts = append(contexts, consoleContext)
+	}
+
+	return contexts, nil
+}
+
+// GetConsoleContext returns a single context by name
+func GetConsoleContext(name string) (ConsoleContext, error) {
+	contexts, err := GetConsoleContexts()
+	if err != nil {
+		return ConsoleContext{}, err
+	}
+
+	for _, consoleContext := range contexts {
+		if consoleContext.Name == strings.ToLower(name) {
+			return consoleContext, nil
+		}
+	}
+
+	return ConsoleContext{}, fmt.Errorf("console context %q does not exist", name)
+}
+
+// GetCurrentConsoleContextName returns the name of the active context
+func GetCurrentConsoleContextName() string {
+	name := viper.GetString(consoleCurrentContextKey)
+	if name == "" {
+		return DefaultConsoleContextName
+	}
+
+	return name
+}
+
+// GetCurrentConsoleContext returns the active context, falling back to the built-in one
+func GetCurrentConsoleContext() (ConsoleContext, error) {
+	return GetConsoleContext(GetCurrentConsoleContextName())
+}
+
+// AddConsoleContext stores a context in the kubefirst config, replacing any context with the same name
+func AddConsoleContext(consoleContext ConsoleContext) error {
+	name := strings.ToLower(consoleContext.Name)
+	if name == "" {
+		return fmt.Errorf("a console context name is required")
+	}
+	if !consoleContextNamePattern.MatchString(name) {
+		return fmt.Errorf("console context name %q is invalid - use lowercase letters, digits and '-'", consoleContext.Name)
+	}
+	if name == DefaultConsoleContextName {
+		return fmt.Errorf("%q is a reserved console context name", DefaultConsoleContextName)
+	}
+	if !strings.HasPrefix(consoleContext.URL, "http://") && !strings.HasPrefix(consoleContext.URL, "https://") {
+		return fmt.Errorf("console url %q must start with http:// or https://", consoleContext.URL)
+	}
+	if consoleContext.CABundle != "" {
+		if _, err := os.Stat(consoleContext.CABundle); err != nil {
+			return fmt.Errorf("unable to read ca bundle %s: %s", consoleContext.CABundle, err)
+		}
+	}
+
+	viper.Set(fmt.Sprintf("%s.%s", consoleContextsKey, name), map[string]interface{}{
+		"url":       strings.TrimSuffix(consoleContext.URL, "/"),
+		"ca-bundle": consoleContext.CABundle,
+		"insecure":  consoleContext.Insecure,
+		"token":     consoleContext.Token,
+	})
+
+	return writeConsoleContexts()
+}
+
+// UseConsoleContext makes the named context the active one
+func UseConsoleContext(name string) error {
+	consoleContext, err := GetConsoleContext(name)
+	if err != nil {
+		return err
+	}
+
+	viper.Set(consoleCurrentContextKey, consoleContext.Name)
+
+	return writeConsoleContexts()
+}
+
+// RemoveConsoleContext deletes a stored context, switching back to the built-in one if it was active
+func RemoveConsoleContext(name string) error {
+	name = strings.ToLower(name)
+	if name == DefaultConsoleContextName {
+		return fmt.Errorf("the %q console context cannot be removed", DefaultConsoleContextName)
+	}
+
+	contexts, err := GetConsoleContexts()
+	if err != nil {
+		return err
+	}
+
+	remaining := map[string]interface{}{}
+	found := false
+	for _, consoleContext := range contexts {
+		switch consoleContext.Name {
+		case DefaultConsoleContextName:
+			continue
+		case name:
+			found = true
+			continue
+		}
+
+		remaining[consoleContext.Name] = map[string]interface{}{
+			"url":       consoleContext.URL,
+			"ca-bundle": consoleContext.CABundle,
+			"insecure":  consoleContext.Insecure,
+			"token":     consoleContext.Token,
+		}
+	}
+	if !found {
+		return fmt.Errorf("console context %q does not exist", name)
+	}
+
+	viper.Set(consoleContextsKey, remaining)
+	if GetCurrentConsoleContextName() == name {
+		viper.Set(consoleCurrentContextKey, DefaultConsoleContextName)
+	}
+
+	return writeConsoleContexts()
+}
+
+// writeConsoleContexts writes the kubefirst config so only the current user can read it,
+// the console tokens are stored in it in plaintext
+func writeConsoleContexts() error {
+	viper.SetConfigPermissions(0600)
+	err := viper.WriteConfig()
+	if err != nil {
+		return err
+	}
+
+	// the permissions only apply to a new file
+	return os.Chmod(viper.ConfigFileUsed(), 0600)
+}
+
+// transport returns an http.Transport honoring the context's CA bundle and insecure setting
+func (cc ConsoleContext) transport() (*http.Transport, error) {
+	customTransport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cc.CABundle == "" && !cc.Insecure {
+		return customTransport, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cc.Insecure,
+	}
+
+	if cc.CABundle != "" {
+		caBundle, err := os.ReadFile(cc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca bundle %s for console context %s: %s", cc.CABundle, cc.Name, err)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificates found in ca bundle %s for console context %s", cc.CABundle, cc.Name)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	customTransport.TLSClientConfig = tlsConfig
+
+	return customTransport, nil
+}
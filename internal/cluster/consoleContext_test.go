@@ -0,0 +1,113 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// useTestConfig points viper at an empty kubefirst config in a temporary directory
+func useTestConfig(t *testing.T) string {
+	configPath := filepath.Join(t.TempDir(), ".kubefirst")
+	err := os.WriteFile(configPath, []byte("{}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configPath)
+	viper.SetConfigType("yaml")
+	t.Cleanup(viper.Reset)
+
+	return configPath
+}
+
+func TestAddConsoleContextNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "staging"},
+		{name: "prod-eu-1"},
+		{name: "Staging"},
+		{name: "", wantErr: true},
+		{name: "prod.eu", wantErr: true},
+		{name: "prod_eu", wantErr: true},
+		{name: "prod eu", wantErr: true},
+		{name: "prod/eu", wantErr: true},
+		{name: DefaultConsoleContextName, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestConfig(t)
+
+			err := AddConsoleContext(ConsoleContext{Name: tt.name, URL: "https://console.example.com"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddConsoleContext(%q) error = %v, want error %t", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			_, err = GetConsoleContext(tt.name)
+			if err != nil {
+				t.Errorf("GetConsoleContext(%q) error = %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestConsoleContextsWriteConfigForUserOnly(t *testing.T) {
+	configPath := useTestConfig(t)
+
+	err := AddConsoleContext(ConsoleContext{Name: "staging", URL: "https://console.example.com", Token: "secret"})
+	if err != nil {
+		t.Fatalf("AddConsoleContext() error = %v", err)
+	}
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("kubefirst config mode = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestRemoveConsoleContext(t *testing.T) {
+	useTestConfig(t)
+
+	for _, name := range []string{"staging", "prod"} {
+		err := AddConsoleContext(ConsoleContext{Name: name, URL: "https://" + name + ".example.com"})
+		if err != nil {
+			t.Fatalf("AddConsoleContext(%q) error = %v", name, err)
+		}
+	}
+	err := UseConsoleContext("staging")
+	if err != nil {
+		t.Fatalf("UseConsoleContext() error = %v", err)
+	}
+
+	err = RemoveConsoleContext("staging")
+	if err != nil {
+		t.Fatalf("RemoveConsoleContext() error = %v", err)
+	}
+	if current := GetCurrentConsoleContextName(); current != DefaultConsoleContextName {
+		t.Errorf("current context after removing it = %q, want %q", current, DefaultConsoleContextName)
+	}
+	if _, err := GetConsoleContext("prod"); err != nil {
+		t.Errorf("GetConsoleContext(prod) error = %v, want the remaining context", err)
+	}
+	if err := RemoveConsoleContext("staging"); err == nil {
+		t.Error("RemoveConsoleContext() of a removed context succeeded, want an error")
+	}
+}
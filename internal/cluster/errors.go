@@ -0,0 +1,62 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound is returned when the console API reports a missing resource
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when the console API reports a conflicting resource
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthorized is returned when the console API rejects the request credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrServerError is returned when the console API fails with a 5xx status
+	ErrServerError = errors.New("server error")
+)
+
+// APIError describes a non-2xx response from the console API and carries the
+// response body so callers can surface the reason to the user
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Is allows errors.Is to match an APIError against the sentinel error for its status class
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrServerError:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+
+	return false
+}
+
+// IsNotFound reports whether err was caused by a 404 from the console API
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
@@ -7,6 +7,7 @@ See the LICENSE file for more details.
 package common
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -48,9 +49,9 @@ func CheckForVersionUpdate() {
 			if res.Outdated {
 				switch runtime.GOOS {
 				case "darwin":
-					fmt.Printf("A newer version (v%s) is available! Please upgrade with: \"brew update && brew upgrade kubefirst\"\n", res.Current)
+					fmt.Fprintf(progress.MessageWriter(), "A newer version (v%s) is available! Please upgrade with: \"brew update && brew upgrade kubefirst\"\n", res.Current)
 				default:
-					fmt.Printf("A newer version (v%s) is available! \"https://github.com/kubefirst/kubefirst/blob/main/build/README.md\"\n", res.Current)
+					fmt.Fprintf(progress.MessageWriter(), "A newer version (v%s) is available! \"https://github.com/kubefirst/kubefirst/blob/main/build/README.md\"\n", res.Current)
 				}
 			}
 		}
@@ -64,25 +65,25 @@ func versionCheck() (res *CheckResponse, skip bool) {
 	resp, err := http.Get("https://raw.githubusercontent.com/Homebrew/homebrew-core/master/Formula/k/kubefirst.rb")
 
 	if err != nil {
-		fmt.Printf("checking for a newer version failed (cannot get Homebrew formula) with: %s", err)
+		fmt.Fprintf(progress.MessageWriter(), "checking for a newer version failed (cannot get Homebrew formula) with: %s", err)
 		return nil, true
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("checking for a newer version failed (HTTP error) with: %s", err)
+		fmt.Fprintf(progress.MessageWriter(), "checking for a newer version failed (HTTP error) with: %s", err)
 		return nil, true
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Printf("checking for a newer version failed (cannot read the file) with: %s", err)
+		fmt.Fprintf(progress.MessageWriter(), "checking for a newer version failed (cannot read the file) with: %s", err)
 		return nil, true
 	}
 
 	bodyString := string(bodyBytes)
 	if !strings.Contains(bodyString, "url \"https://github.com/kubefirst/kubefirst/archive/refs/tags/") {
-		fmt.Printf("checking for a newer version failed (no reference to kubefirst release) with: %s", err)
+		fmt.Fprintf(progress.MessageWriter(), "checking for a newer version failed (no reference to kubefirst release) with: %s", err)
 		return nil, true
 	}
 
@@ -101,7 +102,13 @@ func versionCheck() (res *CheckResponse, skip bool) {
 func GetRootCredentials(cmd *cobra.Command, args []string) error {
 	clusterName := viper.GetString("flags.cluster-name")
 
-	cluster, err := cluster.GetCluster(clusterName)
+	consoleClient, err := cluster.NewDefaultConsoleClient()
+	if err != nil {
+		progress.Error(err.Error())
+		return err
+	}
+
+	cluster, err := consoleClient.GetCluster(context.Background(), clusterName)
 	if err != nil {
 		progress.Error(err.Error())
 		return err
@@ -0,0 +1,138 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitShim
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/kubefirst/kubefirst-api/pkg/handlers"
+	apiTypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/runtime/pkg/github"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/kubefirst/runtime/pkg/services"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// gitHubProvider creates repositories and teams in a github organization
+type gitHubProvider struct {
+	token string
+	owner string
+}
+
+func (g *gitHubProvider) ValidateCredentials() (apiTypes.GitAuth, error) {
+	gitAuth := apiTypes.GitAuth{}
+
+	if g.owner == "" {
+		return gitAuth, fmt.Errorf("please provide a github organization using the --github-org flag")
+	}
+	if g.token == "" {
+		return gitAuth, fmt.Errorf("your GITHUB_TOKEN is not set. Please set and try again")
+	}
+
+	gitAuth.Owner = g.owner
+	gitAuth.Token = g.token
+
+	// Verify token scopes
+	err := github.VerifyTokenPermissions(gitAuth.Token)
+	if err != nil {
+		return gitAuth, err
+	}
+
+	// Handle authorization checks
+	httpClient := http.DefaultClient
+	gitHubService := services.NewGitHubService(httpClient)
+	gitHubHandler := handlers.NewGitHubHandler(gitHubService)
+
+	// get github data to set user based on the provided token
+	log.Info().Msg("verifying github authentication")
+	githubUser, err := gitHubHandler.GetGitHubUser(gitAuth.Token)
+	if err != nil {
+		return gitAuth, err
+	}
+
+	gitAuth.User = githubUser
+	viper.Set("github.user", githubUser)
+	err = viper.WriteConfig()
+	if err != nil {
+		return gitAuth, err
+	}
+	err = gitHubHandler.CheckGithubOrganizationPermissions(gitAuth.Token, g.owner, githubUser)
+	if err != nil {
+		return gitAuth, err
+	}
+	viper.Set("flags.github-owner", g.owner)
+	viper.WriteConfig()
+
+	return gitAuth, nil
+}
+
+func (g *gitHubProvider) RepositoryExists(name string) (bool, error) {
+	// https://docs.github.com/en/rest/repos/repos?apiVersion=2022-11-28#get-a-repository
+	return statusExists(github.New(g.token).CheckRepoExists(g.owner, name), g.RepositoryURL(name))
+}
+
+func (g *gitHubProvider) RepositoryURL(name string) string {
+	return fmt.Sprintf("https://github.com/%s/%s", g.owner, name)
+}
+
+func (g *gitHubProvider) TeamExists(name string) (bool, error) {
+	// https://docs.github.com/en/rest/teams/teams?apiVersion=2022-11-28#get-a-team-by-name
+	return statusExists(github.New(g.token).CheckTeamExists(g.owner, name), g.TeamURL(name))
+}
+
+func (g *gitHubProvider) TeamURL(name string) string {
+	return fmt.Sprintf("https://github.com/orgs/%s/teams/%s", g.owner, name)
+}
+
+// CreateContainerRegistryAuth creates the docker config kaniko requires, for github it
+// carries the provided token (pat)
+func (g *gitHubProvider) CreateContainerRegistryAuth(obj *ContainerRegistryAuth) (string, error) {
+	createDockerConfigSecret(obj, obj.GithubOwner)
+
+	return "", nil
+}
+
+// statusExists reads the status code of a lookup by name
+func statusExists(statusCode int, url string) (bool, error) {
+	switch statusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status %d looking up %s", statusCode, url)
+	}
+}
+
+// createDockerConfigSecret creates the argo workflows pull secret from the git user and token
+func createDockerConfigSecret(obj *ContainerRegistryAuth, username string) {
+	usernamePasswordString := fmt.Sprintf("%s:%s", obj.GitUser, obj.GitToken)
+	usernamePasswordStringB64 := base64.StdEncoding.EncodeToString([]byte(usernamePasswordString))
+	dockerConfigString := fmt.Sprintf(`{"auths": {"%s": {"username": "%s", "password": "%s", "email": "%s", "auth": "%s"}}}`,
+		obj.ContainerRegistryHost,
+		username,
+		obj.GitToken,
+		"k-bot@example.com",
+		usernamePasswordStringB64,
+	)
+
+	// Create argo workflows pull secret
+	argoDeployTokenSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "argo"},
+		Data:       map[string][]byte{"config.json": []byte(dockerConfigString)},
+		Type:       "Opaque",
+	}
+	err := k8s.CreateSecretV2(obj.Clientset, argoDeployTokenSecret)
+	if err != nil {
+		log.Error().Msgf("error while creating secret for container registry auth: %s", err)
+	}
+}
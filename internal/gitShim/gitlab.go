@@ -0,0 +1,152 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitShim
+
+import (
+	"fmt"
+
+	apiTypes "github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/runtime/pkg/gitlab"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// gitLabProvider creates projects and subgroups in a gitlab group
+type gitLabProvider struct {
+	token string
+	group string
+
+	// client, projectNames and subgroupNames are loaded on first use
+	client        *gitlab.GitLabWrapper
+	projectNames  map[string]bool
+	subgroupNames map[string]bool
+}
+
+// wrapper returns the gitlab client, resolving the group on first use
+func (g *gitLabProvider) wrapper() (*gitlab.GitLabWrapper, error) {
+	if g.client != nil {
+		return g.client, nil
+	}
+
+	gitlabClient, err := gitlab.NewGitLabClient(g.token, g.group)
+	if err != nil {
+		return nil, err
+	}
+	g.client = &gitlabClient
+
+	return g.client, nil
+}
+
+func (g *gitLabProvider) ValidateCredentials() (apiTypes.GitAuth, error) {
+	gitAuth := apiTypes.GitAuth{}
+
+	if g.group == "" {
+		return gitAuth, fmt.Errorf("please provide a gitlab group using the --gitlab-group flag")
+	}
+	if g.token == "" {
+		return gitAuth, fmt.Errorf("your GITLAB_TOKEN is not set. please set and try again")
+	}
+
+	gitAuth.Token = g.token
+
+	// Verify token scopes
+	err := gitlab.VerifyTokenPermissions(gitAuth.Token)
+	if err != nil {
+		return gitAuth, err
+	}
+
+	gitlabClient, err := g.wrapper()
+	if err != nil {
+		return gitAuth, err
+	}
+
+	gitAuth.Owner = gitlabClient.ParentGroupPath
+	cGitlabOwnerGroupID := gitlabClient.ParentGroupID
+	log.Info().Msgf("set gitlab owner to %s", gitAuth.Owner)
+
+	// Get authenticated user's name
+	user, _, err := gitlabClient.Client.Users.CurrentUser()
+	if err != nil {
+		return gitAuth, fmt.Errorf("unable to get authenticated user info - please make sure GITLAB_TOKEN env var is set %s", err)
+	}
+	gitAuth.User = user.Username
+
+	viper.Set("flags.gitlab-owner", g.group)
+	viper.Set("flags.gitlab-owner-group-id", cGitlabOwnerGroupID)
+	viper.WriteConfig()
+
+	return gitAuth, nil
+}
+
+func (g *gitLabProvider) RepositoryExists(name string) (bool, error) {
+	if g.projectNames == nil {
+		gitlabClient, err := g.wrapper()
+		if err != nil {
+			return false, err
+		}
+		projects, err := gitlabClient.GetProjects()
+		if err != nil {
+			return false, fmt.Errorf("couldn't get gitlab projects: %s", err)
+		}
+		g.projectNames = map[string]bool{}
+		for _, project := range projects {
+			g.projectNames[project.Name] = true
+		}
+	}
+
+	return g.projectNames[name], nil
+}
+
+func (g *gitLabProvider) RepositoryURL(name string) string {
+	return fmt.Sprintf("https://gitlab.com/%s/%s", g.group, name)
+}
+
+// TeamExists checks the subgroups of the group, kubefirst creates teams as subgroups on gitlab
+func (g *gitLabProvider) TeamExists(name string) (bool, error) {
+	if g.subgroupNames == nil {
+		gitlabClient, err := g.wrapper()
+		if err != nil {
+			return false, err
+		}
+		subgroups, err := gitlabClient.GetSubGroups()
+		if err != nil {
+			return false, fmt.Errorf("couldn't get gitlab subgroups for group %s: %s", g.group, err)
+		}
+		g.subgroupNames = map[string]bool{}
+		for _, subgroup := range subgroups {
+			g.subgroupNames[subgroup.Name] = true
+		}
+	}
+
+	return g.subgroupNames[name], nil
+}
+
+func (g *gitLabProvider) TeamURL(name string) string {
+	return fmt.Sprintf("https://gitlab.com/groups/%s/%s", g.group, name)
+}
+
+// CreateContainerRegistryAuth creates a group deploy token that authorizes against the
+// gitlab container registry
+func (g *gitLabProvider) CreateContainerRegistryAuth(obj *ContainerRegistryAuth) (string, error) {
+	gitlabClient, err := gitlab.NewGitLabClient(obj.GitToken, obj.GitlabGroupFlag)
+	if err != nil {
+		return "", err
+	}
+
+	// Create argo workflows pull secret
+	var p = gitlab.DeployTokenCreateParameters{
+		Name:     secretName,
+		Username: secretName,
+		Scopes:   []string{"read_registry", "write_registry"},
+	}
+	token, err := gitlabClient.CreateGroupDeployToken(0, &p)
+	if err != nil {
+		log.Error().Msgf("error while creating secret for container registry auth: %s", err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitShim
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// ConflictRepository is a repository, a project on gitlab, that already exists
+	ConflictRepository = "repository"
+	// ConflictTeam is a github team that already exists
+	ConflictTeam = "team"
+	// ConflictSubgroup is a gitlab subgroup that already exists, kubefirst creates teams as subgroups on gitlab
+	ConflictSubgroup = "subgroup"
+)
+
+// PreflightConflict is a repository, team or subgroup that keeps kubefirst from continuing
+type PreflightConflict struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Name   string `json:"name" yaml:"name"`
+	URL    string `json:"url" yaml:"url"`
+	Reason string `json:"reason" yaml:"reason"`
+	Fix    string `json:"fix" yaml:"fix"`
+}
+
+// PreflightReport is the result of checking the git provider for the repositories and teams
+// kubefirst is about to create
+type PreflightReport struct {
+	GitProvider string              `json:"git_provider" yaml:"git_provider"`
+	Owner       string              `json:"owner" yaml:"owner"`
+	Conflicts   []PreflightConflict `json:"conflicts" yaml:"conflicts"`
+}
+
+// PreflightError is returned by InitializeGitProvider when the preflight report has conflicts
+type PreflightError struct {
+	Report PreflightReport
+}
+
+func (e *PreflightError) Error() string {
+	conflicts := []string{}
+	for _, conflict := range e.Report.Conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("- %s `%s` %s - %s\n  - how to fix: %s", conflict.Kind, conflict.Name, conflict.Reason, conflict.URL, conflict.Fix))
+	}
+
+	return fmt.Sprintf("the following repositories and teams must be resolved before continuing with your kubefirst installation.\n\n%s", strings.Join(conflicts, "\n"))
+}
+
+// Preflight checks every repository and team of p against the git provider, it collects all
+// conflicts into the report and only returns an error when the provider can't be read
+func Preflight(p *GitInitParameters) (PreflightReport, error) {
+	report := PreflightReport{
+		GitProvider: p.GitProvider,
+		Owner:       p.GitOwner,
+		Conflicts:   []PreflightConflict{},
+	}
+
+	provider, err := NewProvider(ProviderParameters{
+		GitProvider: p.GitProvider,
+		GitToken:    p.GitToken,
+		GitOwner:    p.GitOwner,
+	})
+	if err != nil {
+		return report, err
+	}
+
+	// only k3d create takes other names, the kubefirst api creates the default ones
+	repositoryFix := "delete the repository"
+	teamFix := "delete the %s"
+	if viper.GetString("kubefirst.cloud-provider") == "k3d" {
+		repositoryFix += ", or choose other names with --git-name-prefix, --gitops-repo-name or --metaphor-repo-name"
+		teamFix += ", or choose other names with --git-name-prefix, --admin-team-name or --developer-team-name"
+	}
+
+	for _, repositoryName := range p.Repositories {
+		repositoryURL := provider.RepositoryURL(repositoryName)
+		exists, err := provider.RepositoryExists(repositoryName)
+		if err != nil {
+			return report, err
+		}
+
+		if !exists {
+			log.Info().Msgf("repository %s does not exist, continuing", repositoryURL)
+			continue
+		}
+		log.Info().Msgf("repository %s exists", repositoryURL)
+		report.Conflicts = append(report.Conflicts, PreflightConflict{
+			Kind:   ConflictRepository,
+			Name:   repositoryName,
+			URL:    repositoryURL,
+			Reason: "already exists",
+			Fix:    repositoryFix,
+		})
+	}
+
+	teamKind := ConflictTeam
+	if p.GitProvider == "gitlab" {
+		teamKind = ConflictSubgroup
+	}
+	for _, teamName := range p.Teams {
+		teamURL := provider.TeamURL(teamName)
+		exists, err := provider.TeamExists(teamName)
+		if err != nil {
+			return report, err
+		}
+
+		if !exists {
+			log.Info().Msgf("%s %s does not exist, continuing", teamKind, teamURL)
+			continue
+		}
+		log.Info().Msgf("%s %s exists", teamKind, teamURL)
+		report.Conflicts = append(report.Conflicts, PreflightConflict{
+			Kind:   teamKind,
+			Name:   teamName,
+			URL:    teamURL,
+			Reason: "already exists",
+			Fix:    fmt.Sprintf(teamFix, teamKind),
+		})
+	}
+
+	return report, nil
+}
+
+// DisplayInitError reports an error of InitializeGitProvider, a preflight report is written
+// as a document when structured output is selected and listed by the progress ui otherwise
+func DisplayInitError(err error) {
+	var preflightErr *PreflightError
+	if errors.As(err, &preflightErr) && progress.IsStructuredOutput() {
+		progress.SetExitCode(1)
+		err = progress.Document(preflightErr.Report)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	progress.Error(err.Error())
+}
@@ -0,0 +1,60 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package gitShim
+
+import (
+	"fmt"
+	"strings"
+
+	apiTypes "github.com/kubefirst/kubefirst-api/pkg/types"
+)
+
+// SupportedGitProviders are the values accepted by --git-provider
+var SupportedGitProviders = []string{"github", "gitlab"}
+
+// Provider is a git host kubefirst creates its repositories and teams on
+type Provider interface {
+	// ValidateCredentials verifies the token and its access to the owner and returns the
+	// credentials the cluster is created with
+	ValidateCredentials() (apiTypes.GitAuth, error)
+	// RepositoryExists reports whether the owner already has a repository with this name
+	RepositoryExists(name string) (bool, error)
+	// RepositoryURL is the browser url of a repository of the owner
+	RepositoryURL(name string) string
+	// TeamExists reports whether the owner already has a team with this name
+	TeamExists(name string) (bool, error)
+	// TeamURL is the browser url of a team of the owner
+	TeamURL(name string) string
+	// CreateContainerRegistryAuth creates the secret argo workflows pushes images with and
+	// returns the deploy token of providers that issue one
+	CreateContainerRegistryAuth(obj *ContainerRegistryAuth) (string, error)
+}
+
+// ProviderParameters select and configure a Provider
+type ProviderParameters struct {
+	GitProvider string
+	GitToken    string
+	// GitOwner is the github organization or the gitlab group
+	GitOwner string
+}
+
+// NewProvider returns the Provider for a --git-provider value
+func NewProvider(p ProviderParameters) (Provider, error) {
+	switch p.GitProvider {
+	case "github":
+		return &gitHubProvider{token: p.GitToken, owner: p.GitOwner}, nil
+	case "gitlab":
+		return &gitLabProvider{token: p.GitToken, group: p.GitOwner}, nil
+	default:
+		return nil, fmt.Errorf("invalid git provider %q, must be one of %s", p.GitProvider, strings.Join(SupportedGitProviders, ", "))
+	}
+}
+
+// TokenEnv is the environment variable the token of a git provider is read from
+func TokenEnv(gitProvider string) string {
+	return fmt.Sprintf("%s_TOKEN", strings.ToUpper(gitProvider))
+}
@@ -0,0 +1,397 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package helm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// ErrReleaseNotFound is returned when a release is not installed
+var ErrReleaseNotFound = errors.New("release not found")
+
+// Error is a failed helm action
+type Error struct {
+	Action  string
+	Release string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("helm %s %s failed: %s", e.Action, e.Release, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Chart locates a chart either in a repository or as a packaged chart on disk
+type Chart struct {
+	Name    string
+	RepoURL string
+	Version string
+	// Path is a packaged chart, it takes precedence over the repository
+	Path string
+}
+
+// Options control how install, upgrade and rollback wait for the release
+type Options struct {
+	CreateNamespace bool
+	// Wait blocks until the release resources are ready, Timeout bounds the wait
+	Wait    bool
+	Timeout time.Duration
+	// ReuseValues keeps the values of the installed release on upgrade, the values
+	// passed to Upgrade are applied on top of them
+	ReuseValues bool
+}
+
+// Client installs and manages releases in the cluster of a kubeconfig with the Helm SDK,
+// releases are stored the same way the helm cli stores them so both can manage them
+type Client struct {
+	kubeconfigPath string
+	settings       *cli.EnvSettings
+}
+
+// NewClient returns a client for the cluster of kubeconfigPath, the current context of the
+// default kubeconfig when it is empty
+func NewClient(kubeconfigPath string) *Client {
+	settings := cli.New()
+	settings.KubeConfig = kubeconfigPath
+
+	return &Client{
+		kubeconfigPath: kubeconfigPath,
+		settings:       settings,
+	}
+}
+
+// Install installs a chart as a new release
+func (c *Client) Install(name string, namespace string, chart Chart, values Values, options Options) (*HelmRelease, error) {
+	cfg, err := c.configuration("install", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	install := action.NewInstall(cfg)
+	install.ReleaseName = name
+	install.Namespace = namespace
+	install.CreateNamespace = options.CreateNamespace
+	install.Wait = options.Wait
+	install.Timeout = options.timeout()
+	install.RepoURL = chart.RepoURL
+	install.Version = chart.Version
+
+	loaded, vals, err := c.load("install", name, install.ChartPathOptions, chart, values)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("running helm install %s", name)
+	installed, err := install.Run(loaded, vals)
+	if err != nil {
+		return nil, failure("install", name, err)
+	}
+
+	return toHelmRelease(installed), nil
+}
+
+// Upgrade moves a release to a chart version and values
+func (c *Client) Upgrade(name string, namespace string, chart Chart, values Values, options Options) (*HelmRelease, error) {
+	cfg, err := c.configuration("upgrade", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = namespace
+	upgrade.Wait = options.Wait
+	upgrade.Timeout = options.timeout()
+	upgrade.ReuseValues = options.ReuseValues
+	upgrade.RepoURL = chart.RepoURL
+	upgrade.Version = chart.Version
+
+	loaded, vals, err := c.load("upgrade", name, upgrade.ChartPathOptions, chart, values)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Msgf("running helm upgrade %s", name)
+	upgraded, err := upgrade.Run(name, loaded, vals)
+	if err != nil {
+		return nil, failure("upgrade", name, err)
+	}
+
+	return toHelmRelease(upgraded), nil
+}
+
+// Rollback returns a release to a revision, 0 rolls back to the previous revision
+func (c *Client) Rollback(name string, namespace string, revision int, options Options) (*HelmRelease, error) {
+	cfg, err := c.configuration("rollback", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback := action.NewRollback(cfg)
+	rollback.Version = revision
+	rollback.Wait = options.Wait
+	rollback.Timeout = options.timeout()
+
+	log.Info().Msgf("running helm rollback %s", name)
+	err = rollback.Run(name)
+	if err != nil {
+		return nil, failure("rollback", name, err)
+	}
+
+	return c.Status(name, namespace)
+}
+
+// Uninstall removes a release
+func (c *Client) Uninstall(name string, namespace string) error {
+	cfg, err := c.configuration("uninstall", name, namespace)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("running helm uninstall %s", name)
+	_, err = action.NewUninstall(cfg).Run(name)
+	if err != nil {
+		return failure("uninstall", name, err)
+	}
+
+	return nil
+}
+
+// Status returns a release, ErrReleaseNotFound when it is not installed
+func (c *Client) Status(name string, namespace string) (*HelmRelease, error) {
+	releases, err := c.List(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if release.Name == name {
+			return &release, nil
+		}
+	}
+
+	return nil, &Error{Action: "status", Release: name, Err: ErrReleaseNotFound}
+}
+
+// List returns the releases in a namespace, every namespace when it is empty
+func (c *Client) List(namespace string) ([]HelmRelease, error) {
+	cfg, err := c.configuration("list", namespace, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	list := action.NewList(cfg)
+	list.All = true
+	list.AllNamespaces = namespace == ""
+	list.SetStateMask()
+
+	listed, err := list.Run()
+	if err != nil {
+		return nil, failure("list", namespace, err)
+	}
+
+	releases := []HelmRelease{}
+	for _, listedRelease := range listed {
+		releases = append(releases, *toHelmRelease(listedRelease))
+	}
+
+	return releases, nil
+}
+
+// History returns the revisions of a release, oldest first
+func (c *Client) History(name string, namespace string) ([]Revision, error) {
+	cfg, err := c.configuration("history", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	history := action.NewHistory(cfg)
+	history.Max = 256
+
+	releases, err := history.Run(name)
+	if err != nil {
+		return nil, failure("history", name, err)
+	}
+	releaseutil.SortByRevision(releases)
+
+	revisions := []Revision{}
+	for _, revision := range releases {
+		revisions = append(revisions, Revision{
+			Revision:    revision.Version,
+			Updated:     revision.Info.LastDeployed.String(),
+			Status:      revision.Info.Status.String(),
+			Chart:       chartName(revision.Chart),
+			AppVersion:  appVersion(revision.Chart),
+			Description: revision.Info.Description,
+		})
+	}
+
+	return revisions, nil
+}
+
+// GetValues returns the values a release was installed or upgraded with, all computed
+// values including chart defaults when all is set
+func (c *Client) GetValues(name string, namespace string, all bool) (Values, error) {
+	cfg, err := c.configuration("get values", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	getValues := action.NewGetValues(cfg)
+	getValues.AllValues = all
+
+	released, err := getValues.Run(name)
+	if err != nil {
+		return nil, failure("get values", name, err)
+	}
+
+	// a release without user supplied values has no values at all
+	if released == nil {
+		return Values{}, nil
+	}
+
+	return Values(released), nil
+}
+
+// Pull downloads a packaged chart from its repository into destination
+func (c *Client) Pull(chart Chart, destination string) error {
+	pull := action.NewPullWithOpts(action.WithConfig(&action.Configuration{}))
+	pull.Settings = c.settings
+	pull.RepoURL = chart.RepoURL
+	pull.Version = chart.Version
+	pull.DestDir = destination
+
+	log.Info().Msgf("running helm pull %s", chart.Name)
+	_, err := pull.Run(chart.Name)
+	if err != nil {
+		return failure("pull", chart.Name, err)
+	}
+
+	return nil
+}
+
+// configuration returns the helm action configuration for a namespace, the release
+// storage driver follows HELM_DRIVER like the helm cli
+func (c *Client) configuration(actionName string, release string, namespace string) (*action.Configuration, error) {
+	cfg := &action.Configuration{}
+	getter := kube.GetConfig(c.kubeconfigPath, "", namespace)
+	err := cfg.Init(getter, namespace, os.Getenv("HELM_DRIVER"), func(format string, v ...interface{}) {
+		log.Debug().Msgf(format, v...)
+	})
+	if err != nil {
+		return nil, &Error{Action: actionName, Release: release, Err: err}
+	}
+
+	return cfg, nil
+}
+
+// load locates the chart, downloading it from its repository unless it is packaged on disk,
+// and renders values the way helm reads a values file
+func (c *Client) load(actionName string, name string, pathOptions action.ChartPathOptions, source Chart, values Values) (*chart.Chart, map[string]interface{}, error) {
+	chartPath := source.Path
+	if chartPath == "" {
+		var err error
+		chartPath, err = pathOptions.LocateChart(source.Name, c.settings)
+		if err != nil {
+			return nil, nil, failure(actionName, name, err)
+		}
+	}
+
+	loaded, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, nil, failure(actionName, name, err)
+	}
+
+	vals, err := renderValues(values)
+	if err != nil {
+		return nil, nil, &Error{Action: actionName, Release: name, Err: err}
+	}
+
+	return loaded, vals, nil
+}
+
+// failure logs and wraps an error of the Helm SDK
+func failure(actionName string, release string, err error) error {
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		err = ErrReleaseNotFound
+	}
+	log.Error().Msgf("helm %s %s failed: %s", actionName, release, err)
+
+	return &Error{Action: actionName, Release: release, Err: err}
+}
+
+// timeout is the wait timeout of the options, the helm cli default when it is not set
+func (o Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+
+	return 5 * time.Minute
+}
+
+// renderValues converts values to the plain maps the Helm SDK expects by reading them back
+// as a values file, nested Values are not recognized as maps when values are coalesced
+func renderValues(values Values) (map[string]interface{}, error) {
+	content, err := yaml.Marshal(map[string]interface{}(values))
+	if err != nil {
+		return nil, fmt.Errorf("could not render values: %s", err)
+	}
+
+	rendered, err := chartutil.ReadValues(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not render values: %s", err)
+	}
+
+	return rendered, nil
+}
+
+func toHelmRelease(r *release.Release) *HelmRelease {
+	return &HelmRelease{
+		AppVersion: appVersion(r.Chart),
+		Chart:      chartName(r.Chart),
+		Name:       r.Name,
+		Namespace:  r.Namespace,
+		Revision:   strconv.Itoa(r.Version),
+		Status:     r.Info.Status.String(),
+		Updated:    r.Info.LastDeployed.String(),
+	}
+}
+
+// chartName is the chart of a release as helm lists it, e.g. kubefirst-2.2.0
+func chartName(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%s", c.Metadata.Name, c.Metadata.Version)
+}
+
+func appVersion(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return ""
+	}
+
+	return c.Metadata.AppVersion
+}
@@ -6,17 +6,22 @@ See the LICENSE file for more details.
 */
 package helm
 
-type HelmRepo struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
+type HelmRelease struct {
+	AppVersion string `json:"app_version" yaml:"app_version"`
+	Chart      string `json:"chart" yaml:"chart"`
+	Name       string `json:"name" yaml:"name"`
+	Namespace  string `json:"namespace" yaml:"namespace"`
+	Revision   string `json:"revision" yaml:"revision"`
+	Status     string `json:"status" yaml:"status"`
+	Updated    string `json:"updated" yaml:"updated"`
 }
 
-type HelmRelease struct {
-	AppVersion string `yaml:"app_version"`
-	Chart      string `yaml:"chart"`
-	Name       string `yaml:"name"`
-	Namespace  string `yaml:"namespace"`
-	Revision   string `yaml:"revision"`
-	Status     string `yaml:"status"`
-	Updated    string `yaml:"updated"`
+// Revision is an entry in the history of a release
+type Revision struct {
+	Revision    int    `json:"revision" yaml:"revision"`
+	Updated     string `json:"updated" yaml:"updated"`
+	Status      string `json:"status" yaml:"status"`
+	Chart       string `json:"chart" yaml:"chart"`
+	AppVersion  string `json:"app_version" yaml:"app_version"`
+	Description string `json:"description" yaml:"description"`
 }
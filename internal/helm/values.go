@@ -0,0 +1,190 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package helm
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Values are chart values nested the same way as in a values file
+type Values map[string]interface{}
+
+// Set assigns a value at a dotted path, e.g. Set("global.domainName", "kubefirst.dev")
+func (v Values) Set(path string, value interface{}) {
+	keys := strings.Split(path, ".")
+
+	current := v
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(Values)
+		if !ok {
+			if nested, isMap := current[key].(map[string]interface{}); isMap {
+				next = Values(nested)
+			} else {
+				next = Values{}
+			}
+			current[key] = next
+		}
+		current = next
+	}
+
+	current[keys[len(keys)-1]] = value
+}
+
+// Get returns the value at a dotted path
+func (v Values) Get(path string) (interface{}, bool) {
+	var current interface{} = v
+	for _, key := range strings.Split(path, ".") {
+		var nested map[string]interface{}
+		switch typed := current.(type) {
+		case Values:
+			nested = typed
+		case map[string]interface{}:
+			nested = typed
+		default:
+			return nil, false
+		}
+
+		value, exists := nested[key]
+		if !exists {
+			return nil, false
+		}
+		current = value
+	}
+
+	return current, true
+}
+
+// Merge overlays other onto v, nested values are merged key by key
+func (v Values) Merge(other Values) {
+	for key, value := range other {
+		otherNested, otherIsMap := asValues(value)
+		currentNested, currentIsMap := asValues(v[key])
+		if otherIsMap && currentIsMap {
+			currentNested.Merge(otherNested)
+			v[key] = currentNested
+			continue
+		}
+
+		v[key] = value
+	}
+}
+
+func asValues(value interface{}) (Values, bool) {
+	switch typed := value.(type) {
+	case Values:
+		return typed, true
+	case map[string]interface{}:
+		return Values(typed), true
+	}
+
+	return nil, false
+}
+
+// ReadValuesFile reads a helm values file
+func ReadValuesFile(path string) (Values, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read values file %s: %s", path, err)
+	}
+
+	values := Values{}
+	err = yaml.Unmarshal(content, &values)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse values file %s: %s", path, err)
+	}
+
+	return values, nil
+}
+
+// UnmarshalYAML reads nested mappings as map[string]interface{} rather than the
+// map[interface{}]interface{} yaml produces, so values read from files merge with values
+// set in code
+func (v *Values) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	raw := map[interface{}]interface{}{}
+	err := unmarshal(&raw)
+	if err != nil {
+		return err
+	}
+
+	*v = Values(normalize(raw).(map[string]interface{}))
+
+	return nil
+}
+
+// normalize converts the mappings yaml produces to map[string]interface{}
+func normalize(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, nested := range typed {
+			converted[fmt.Sprintf("%v", key)] = normalize(nested)
+		}
+		return converted
+	case []interface{}:
+		for i, nested := range typed {
+			typed[i] = normalize(nested)
+		}
+		return typed
+	}
+
+	return value
+}
+
+// ParseSet reads a helm --set expression such as `a.b=c,d=true` into values, booleans,
+// integers and null are typed the way helm types them
+func ParseSet(expression string) (Values, error) {
+	values := Values{}
+
+	for _, assignment := range splitUnescaped(expression, ',') {
+		if assignment == "" {
+			continue
+		}
+
+		path, raw, found := strings.Cut(assignment, "=")
+		if !found || path == "" {
+			return nil, fmt.Errorf("invalid helm value %q, expected key=value", assignment)
+		}
+
+		values.Set(path, typedValue(strings.ReplaceAll(raw, `\,`, ",")))
+	}
+
+	return values, nil
+}
+
+func splitUnescaped(expression string, separator byte) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(expression); i++ {
+		if expression[i] == separator && (i == 0 || expression[i-1] != '\\') {
+			parts = append(parts, expression[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, expression[start:])
+}
+
+func typedValue(raw string) interface{} {
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if number, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return number
+	}
+
+	return raw
+}
@@ -110,6 +110,7 @@ func MongoDestinationChooser(inCluster bool) string {
 	items := []list.Item{
 		Item("in-cluster"),
 		Item("atlas"),
+		Item("external"),
 	}
 
 	l := list.New(items, ItemDelegate{}, DefaultWidth, ListHeight)
@@ -132,6 +133,8 @@ func MongoDestinationChooser(inCluster bool) string {
 
 	if strings.Contains(model.View(), "atlas") {
 		result = "atlas"
+	} else if strings.Contains(model.View(), "external") {
+		result = "external"
 	} else if strings.Contains(model.View(), "in-cluster") {
 		result = "in-cluster"
 	} else {
@@ -0,0 +1,546 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/runtime/configs"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BackupPassphraseEnv holds the backup passphrase when --passphrase-file is not set
+	BackupPassphraseEnv = "KUBEFIRST_BACKUP_PASSPHRASE"
+
+	backupVersion = 1
+	// backupDatabase is the database the kubefirst api stores its records in
+	backupDatabase = "api"
+
+	// scrypt parameters for deriving the backup key from the passphrase
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// encryptedBackup is the file launch backup writes
+type encryptedBackup struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	// Ciphertext is the AES-256-GCM encrypted backupContent
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// backupContent holds every document of the backed up collections as canonical extended json
+type backupContent struct {
+	KubefirstVersion string                       `json:"kubefirst_version"`
+	CreatedAt        time.Time                    `json:"created_at"`
+	Collections      map[string][]json.RawMessage `json:"collections"`
+}
+
+// Backup exports every collection of the console api database, its clusters, services,
+// environments and gitops catalog, to a passphrase encrypted file
+func Backup(outputPath string, passphraseFile string) {
+	passphrase, err := backupPassphrase(passphraseFile, true)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	progress.AddStep("Connect to console database")
+
+	client, closeDatabase, err := connectConsoleDatabase()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	defer closeDatabase()
+
+	progress.CompleteStep("Connect to console database")
+	progress.AddStep("Export console records")
+
+	content := backupContent{
+		KubefirstVersion: configs.K1Version,
+		CreatedAt:        time.Now().UTC(),
+		Collections:      map[string][]json.RawMessage{},
+	}
+	collections, err := client.Database(backupDatabase).ListCollectionNames(context.Background(), bson.D{})
+	if err != nil {
+		progress.Error(fmt.Sprintf("error listing the collections of the console database: %s", err))
+		return
+	}
+	for _, collection := range collections {
+		// system collections belong to mongodb, not the api
+		if strings.HasPrefix(collection, "system.") {
+			continue
+		}
+		documents, err := exportCollection(client.Database(backupDatabase).Collection(collection))
+		if err != nil {
+			progress.Error(fmt.Sprintf("error exporting %s: %s", collection, err))
+			return
+		}
+		content.Collections[collection] = documents
+	}
+
+	plaintext, err := json.Marshal(content)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	encrypted, err := encryptBackup(plaintext, passphrase)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	err = os.WriteFile(outputPath, encrypted, 0600)
+	if err != nil {
+		progress.Error(fmt.Sprintf("error writing backup %s: %s", outputPath, err))
+		return
+	}
+
+	progress.CompleteStep("Export console records")
+
+	displayBackupSummary(content, fmt.Sprintf("### :floppy_disk: Backed up the console to `%s`", outputPath))
+}
+
+// Restore imports the records of a backup into the console database, records that
+// already exist are replaced
+func Restore(inputPath string, passphraseFile string) {
+	encrypted, err := os.ReadFile(inputPath)
+	if err != nil {
+		progress.Error(fmt.Sprintf("error reading backup %s: %s", inputPath, err))
+		return
+	}
+	passphrase, err := backupPassphrase(passphraseFile, false)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	plaintext, err := decryptBackup(encrypted, passphrase)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	content := backupContent{}
+	err = json.Unmarshal(plaintext, &content)
+	if err != nil {
+		progress.Error(fmt.Sprintf("could not read backup %s: %s", inputPath, err))
+		return
+	}
+
+	progress.AddStep("Connect to console database")
+
+	client, closeDatabase, err := connectConsoleDatabase()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+	defer closeDatabase()
+
+	progress.CompleteStep("Connect to console database")
+	progress.AddStep("Import console records")
+
+	for _, collection := range content.collectionNames() {
+		err = importCollection(client.Database(backupDatabase).Collection(collection), content.Collections[collection])
+		if err != nil {
+			progress.Error(fmt.Sprintf("error importing %s: %s", collection, err))
+			return
+		}
+	}
+
+	progress.CompleteStep("Import console records")
+
+	displayBackupSummary(content, fmt.Sprintf("### :recycle: Restored the console from `%s`", inputPath))
+}
+
+func displayBackupSummary(content backupContent, title string) {
+	if progress.IsStructuredOutput() {
+		summary := map[string]interface{}{
+			"kubefirst_version": content.KubefirstVersion,
+			"created_at":        content.CreatedAt,
+		}
+		for _, collection := range content.collectionNames() {
+			summary[collection] = len(content.Collections[collection])
+		}
+		err := progress.Document(summary)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	rows := []string{}
+	for _, collection := range content.collectionNames() {
+		rows = append(rows, fmt.Sprintf("| %s | %d |", collection, len(content.Collections[collection])))
+	}
+
+	progress.Success(fmt.Sprintf(`
+%s
+
+| BACKUP | |
+| --- | --- |
+| created at | %s |
+| kubefirst version | %s |
+%s
+`, title, content.CreatedAt.Format(time.RFC3339), content.KubefirstVersion, strings.Join(rows, "\n")))
+}
+
+// collectionNames returns the collections of a backup in a stable order
+func (c backupContent) collectionNames() []string {
+	names := []string{}
+	for name := range c.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// exportCollection returns every document of a collection as canonical extended json so
+// object ids and dates survive the round trip
+func exportCollection(collection *mongo.Collection) ([]json.RawMessage, error) {
+	ctx := context.Background()
+	cursor, err := collection.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []json.RawMessage{}
+	for cursor.Next(ctx) {
+		document, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	return documents, cursor.Err()
+}
+
+// importCollection upserts documents by their _id
+func importCollection(collection *mongo.Collection, documents []json.RawMessage) error {
+	ctx := context.Background()
+	for _, raw := range documents {
+		document := bson.D{}
+		err := bson.UnmarshalExtJSON(raw, true, &document)
+		if err != nil {
+			return err
+		}
+
+		id, found := documentID(document)
+		if !found {
+			_, err = collection.InsertOne(ctx, document)
+		} else {
+			_, err = collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: id}}, document, options.Replace().SetUpsert(true))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func documentID(document bson.D) (interface{}, bool) {
+	for _, element := range document {
+		if element.Key == "_id" {
+			return element.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// backupPassphrase reads the passphrase from a file, the environment or a prompt, a
+// prompted passphrase for a new backup has to be entered twice
+func backupPassphrase(passphraseFile string, confirm bool) (string, error) {
+	if passphraseFile != "" {
+		content, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("could not read passphrase file: %s", err)
+		}
+		passphrase := strings.TrimRight(string(content), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("passphrase file %s is empty", passphraseFile)
+		}
+
+		return passphrase, nil
+	}
+
+	if passphrase := os.Getenv(BackupPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("a passphrase is required, use --passphrase-file or %s", BackupPassphraseEnv)
+	}
+
+	fmt.Printf("\nBackup passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("error parsing passphrase: %s", err)
+	}
+	if len(passphrase) == 0 {
+		return "", fmt.Errorf("the passphrase must not be empty")
+	}
+	if confirm {
+		fmt.Printf("\nRepeat backup passphrase: ")
+		repeated, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if err != nil {
+			return "", fmt.Errorf("error parsing passphrase: %s", err)
+		}
+		if string(repeated) != string(passphrase) {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	fmt.Println()
+
+	return string(passphrase), nil
+}
+
+// encryptBackup seals plaintext with AES-256-GCM under a key derived from the passphrase
+func encryptBackup(plaintext []byte, passphrase string) ([]byte, error) {
+	backup := encryptedBackup{
+		Version: backupVersion,
+		KDF:     "scrypt",
+		Salt:    make([]byte, 16),
+	}
+	_, err := rand.Read(backup.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := backupCipher(passphrase, backup.Salt)
+	if err != nil {
+		return nil, err
+	}
+	backup.Nonce = make([]byte, aead.NonceSize())
+	_, err = rand.Read(backup.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	backup.Ciphertext = aead.Seal(nil, backup.Nonce, plaintext, nil)
+
+	return json.MarshalIndent(backup, "", "  ")
+}
+
+// decryptBackup opens a backup written by encryptBackup
+func decryptBackup(encrypted []byte, passphrase string) ([]byte, error) {
+	backup := encryptedBackup{}
+	err := json.Unmarshal(encrypted, &backup)
+	if err != nil {
+		return nil, fmt.Errorf("not a kubefirst console backup: %s", err)
+	}
+	if backup.Version != backupVersion || backup.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported backup version %d", backup.Version)
+	}
+
+	aead, err := backupCipher(passphrase, backup.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(backup.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("the backup is corrupted")
+	}
+	plaintext, err := aead.Open(nil, backup.Nonce, backup.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("the passphrase is wrong or the backup is corrupted")
+	}
+
+	return plaintext, nil
+}
+
+func backupCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// connectConsoleDatabase connects to the database of the launched console, through a port
+// forward for the in-cluster database, and returns a function that closes the connection
+func connectConsoleDatabase() (*mongo.Client, func(), error) {
+	if !viper.GetBool("launch.deployed") {
+		return nil, nil, fmt.Errorf("Kubefirst console has not been deployed. Run `kubefirst launch up` to deploy it.")
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, fmt.Errorf("something went wrong getting home path: %s", err)
+	}
+	kubeconfigPath := fmt.Sprintf("%s/.k1/%s/kubeconfig", homeDir, consoleClusterName)
+	kcfg := k8s.CreateKubeConfig(false, kubeconfigPath)
+
+	secret, err := kcfg.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), secretName, metav1.GetOptions{})
+	if err != nil && viper.GetString("launch.database-destination") != "in-cluster" {
+		return nil, nil, fmt.Errorf("could not read the database credentials from secret %s: %s", secretName, err)
+	}
+
+	var client *mongo.Client
+	closeDatabase := func() {}
+	switch viper.GetString("launch.database-destination") {
+	case "in-cluster":
+		client, closeDatabase, err = connectInClusterDatabase(kcfg)
+	case "atlas", "external":
+		var stored *database
+		stored, err = releaseDatabase(kubeconfigPath, string(secret.Data[secretKeyPassword]))
+		if err != nil {
+			return nil, nil, err
+		}
+		client, err = connectDatabase(stored)
+	default:
+		return nil, nil, fmt.Errorf("the console database has not been initialized")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), externalPingTimeout)
+	defer cancel()
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		client.Disconnect(context.Background())
+		closeDatabase()
+		return nil, nil, fmt.Errorf("error connecting to the console database: %s", err)
+	}
+
+	return client, func() {
+		client.Disconnect(context.Background())
+		closeDatabase()
+	}, nil
+}
+
+// releaseDatabase returns the atlas or external database the installed release connects to
+func releaseDatabase(kubeconfigPath string, password string) (*database, error) {
+	values, err := helm.NewClient(kubeconfigPath).GetValues(helmChartName, namespace, false)
+	if err != nil {
+		return nil, err
+	}
+
+	released := &database{
+		hostType: "atlas",
+		password: password,
+	}
+	host, _ := values.Get("kubefirst-api.atlasDbHost")
+	user, _ := values.Get("kubefirst-api.atlasDbUsername")
+	if host == nil || host == "" {
+		released.hostType = "local"
+		host, _ = values.Get("kubefirst-api.mongodbHost")
+		user, _ = values.Get("kubefirst-api.mongodbUsername")
+	}
+	if host == nil || user == nil {
+		return nil, fmt.Errorf("the console release does not name its database host and username")
+	}
+	released.host = fmt.Sprintf("%v", host)
+	released.user = fmt.Sprintf("%v", user)
+
+	return released, nil
+}
+
+// connectInClusterDatabase port forwards to the mongodb pod of the console release
+func connectInClusterDatabase(kcfg *k8s.KubernetesClient) (*mongo.Client, func(), error) {
+	pods, err := kcfg.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=mongodb",
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not find the console database: %s", err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil, fmt.Errorf("the console database is not running in namespace %s", namespace)
+	}
+
+	password, err := inClusterDatabasePassword(kcfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localPort, err := freeLocalPort()
+	if err != nil {
+		return nil, nil, err
+	}
+	stopChannel := make(chan struct{})
+	k8s.OpenPortForwardPodWrapper(
+		kcfg.Clientset,
+		kcfg.RestConfig,
+		pods.Items[0].Name,
+		namespace,
+		27017,
+		localPort,
+		stopChannel,
+	)
+
+	client, err := mongo.Connect(context.Background(), options.Client().
+		ApplyURI(fmt.Sprintf("mongodb://127.0.0.1:%d/?directConnection=true", localPort)).
+		SetAuth(options.Credential{
+			Username:   "root",
+			Password:   password,
+			AuthSource: "admin",
+		}))
+	if err != nil {
+		close(stopChannel)
+		return nil, nil, err
+	}
+
+	return client, func() { close(stopChannel) }, nil
+}
+
+// inClusterDatabasePassword reads the root password of the in-cluster database from the
+// initial secrets, or from the secret the mongodb chart generates
+func inClusterDatabasePassword(kcfg *k8s.KubernetesClient) (string, error) {
+	for _, name := range []string{secretName, fmt.Sprintf("%s-mongodb", helmChartName)} {
+		secret, err := kcfg.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+		if password, ok := secret.Data["mongodb-root-password"]; ok {
+			return string(password), nil
+		}
+	}
+
+	return "", errors.New("could not find the root password of the console database")
+}
+
+func freeLocalPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
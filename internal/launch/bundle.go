@@ -0,0 +1,18 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import "github.com/kubefirst/kubefirst/internal/bundle"
+
+// BundleChart returns the helm chart launch installs so it can be packaged into a bundle
+func BundleChart() bundle.Chart {
+	return bundle.Chart{
+		Name:    helmChartName,
+		RepoURL: helmChartRepoURL,
+		Version: helmChartVersion,
+	}
+}
@@ -8,27 +8,24 @@ package launch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/kubefirst/kubefirst/internal/bundle"
 	"github.com/kubefirst/kubefirst/internal/cluster"
 	"github.com/kubefirst/kubefirst/internal/helm"
-	k3dint "github.com/kubefirst/kubefirst/internal/k3d"
 	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/tools"
 	"github.com/kubefirst/runtime/configs"
 	"github.com/kubefirst/runtime/pkg"
-	"github.com/kubefirst/runtime/pkg/db"
-	"github.com/kubefirst/runtime/pkg/downloadManager"
 	"github.com/kubefirst/runtime/pkg/k3d"
 	"github.com/kubefirst/runtime/pkg/k8s"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
-	"golang.org/x/term"
-	"gopkg.in/yaml.v2"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -42,12 +39,52 @@ var (
 	}
 )
 
+// UpOptions configure how launch up installs the console
+type UpOptions struct {
+	// AdditionalHelmFlags are helm --set expressions applied after ValuesFiles
+	AdditionalHelmFlags []string
+	// ValuesFiles are helm values files applied after the profile values, in order
+	ValuesFiles []string
+	// Profile is the name of a launch profile stored in ~/.k1/profiles
+	Profile string
+	// InCluster installs the console into the cluster kubefirst runs in
+	InCluster    bool
+	UseTelemetry bool
+	// BundlePath installs the tools and chart from a bundle created with `kubefirst tools bundle`
+	BundlePath string
+	// Database selects the console database instead of prompting for it
+	Database DatabaseOptions
+}
+
 // Up
-func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
+func Up(options UpOptions) {
+	inCluster := options.InCluster
+	bundlePath := options.BundlePath
+
 	if viper.GetBool("launch.deployed") {
 		progress.Error("Kubefirst console has already been deployed. To start over, run `kubefirst launch down` to completely remove the existing console.")
 	}
 
+	// Profile and user values are resolved first so a typo fails before anything is installed
+	profile := &Profile{}
+	if options.Profile != "" {
+		var err error
+		profile, err = LoadProfile(options.Profile)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
+	}
+	chartVersion := helmChartVersion
+	if profile.ChartVersion != "" {
+		chartVersion = profile.ChartVersion
+	}
+	overrides, err := userValues(options.ValuesFiles, options.AdditionalHelmFlags)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
 	if !inCluster {
 		progress.DisplayLogHints(10)
 	}
@@ -71,55 +108,39 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 		}
 	}
 
-	dbInitialized := viper.GetBool("launch.database-initialized")
-	var dbHost, dbUser, dbPassword string
-
-	progress.AddStep("Initialize database")
-
-	if !dbInitialized {
-		dbDestination := k3dint.MongoDestinationChooser(inCluster)
-		switch dbDestination {
-		case "atlas":
-			fmt.Println("MongoDB Atlas Host String: ")
-			fmt.Scanln(&dbHost)
-
-			fmt.Printf("\nMongoDB Atlas Username: ")
-			fmt.Scanln(&dbUser)
+	// The bundle replaces every download, it is verified before any prompt
+	var toolsBundle *bundle.Bundle
+	if bundlePath != "" {
+		toolsBundle, err = bundle.Open(bundlePath)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
+		defer toolsBundle.Close()
 
-			fmt.Printf("\nMongoDB Atlas Password: ")
-			dbPasswordInput, err := term.ReadPassword(0)
-			if err != nil {
-				progress.Error(fmt.Sprintf("error parsing password: %s", err))
-			}
+		if toolsBundle.Manifest.Chart.Version != chartVersion {
+			progress.Error(fmt.Sprintf("bundle %s contains chart version %s but chart version %s is being installed - create a new bundle with `kubefirst tools bundle`", bundlePath, toolsBundle.Manifest.Chart.Version, chartVersion))
+			return
+		}
+	}
 
-			dbPassword = string(dbPasswordInput)
-			dbHost = strings.Replace(dbHost, "mongodb+srv://", "", -1)
-
-			// Verify database connectivity
-			mdbcl := db.Connect(&db.MongoDBClientParameters{
-				HostType: dbDestination,
-				Host:     dbHost,
-				Username: dbUser,
-				Password: dbPassword,
-			})
-			err = mdbcl.TestDatabaseConnection()
-			if err != nil {
-				progress.Error(fmt.Sprintf("Error validating Mongo credentials: %s", err))
-			}
-			mdbcl.Client.Disconnect(mdbcl.Context)
+	dbInitialized := viper.GetBool("launch.database-initialized")
+	consoleDatabase := &database{}
 
-			log.Info().Msg("MongoDB Atlas credentials verified")
+	progress.AddStep("Initialize database")
 
-			viper.Set("launch.database-destination", "atlas")
-			viper.Set("launch.database-initialized", true)
-			viper.WriteConfig()
-		case "in-cluster":
-			viper.Set("launch.database-destination", "in-cluster")
-			viper.Set("launch.database-initialized", true)
-			viper.WriteConfig()
-		default:
-			progress.Error(fmt.Sprintf("%s is not a valid option", dbDestination))
+	// an explicit --database resolves the database again, so a rerun after a failed install
+	// still has the atlas credentials
+	if !dbInitialized || options.Database.Destination != "" {
+		consoleDatabase, err = resolveDatabase(options.Database, profile.DatabaseDestination, inCluster)
+		if err != nil {
+			progress.Error(err.Error())
+			return
 		}
+
+		viper.Set("launch.database-destination", consoleDatabase.destination)
+		viper.Set("launch.database-initialized", true)
+		viper.WriteConfig()
 	} else {
 		log.Info().Msg("Database has already been initialized, skipping")
 	}
@@ -130,89 +151,28 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 
 	progress.AddStep("Download k3d")
 
-	// Download k3d
-	k3dClient := fmt.Sprintf("%s/k3d", toolsDir)
-	_, err = os.Stat(k3dClient)
-	if err != nil {
-		log.Info().Msg("Downloading k3d...")
-		k3dDownloadUrl := fmt.Sprintf(
-			"https://github.com/k3d-io/k3d/releases/download/%s/k3d-%s-%s",
-			k3d.K3dVersion,
-			k3d.LocalhostOS,
-			k3d.LocalhostARCH,
-		)
-		err = downloadManager.DownloadFile(k3dClient, k3dDownloadUrl)
-		if err != nil {
-			progress.Error(fmt.Sprintf("error while trying to download k3d: %s", err))
-		}
-		err = os.Chmod(k3dClient, 0755)
-		if err != nil {
-			log.Fatal().Msg(err.Error())
+	// Download k3d and mkcert, or install them from the bundle, unless the pinned version
+	// is already installed and matches its checksum
+	for _, tool := range []tools.Tool{tools.K3d, tools.Mkcert} {
+		if tool.Current(toolsDir) {
+			log.Info().Msgf("%s %s is already installed, continuing", tool.Name, tool.Version)
+			continue
 		}
-	} else {
-		log.Info().Msgf("k3d is already installed, continuing")
-	}
-	progress.CompleteStep("Download k3d")
 
-	// Download helm
-	helmClient := fmt.Sprintf("%s/helm", toolsDir)
-	_, err = os.Stat(helmClient)
-	if err != nil {
-		log.Info().Msg("Downloading helm...")
-		helmVersion := "v3.12.0"
-		helmDownloadUrl := fmt.Sprintf(
-			"https://get.helm.sh/helm-%s-%s-%s.tar.gz",
-			helmVersion,
-			k3d.LocalhostOS,
-			k3d.LocalhostARCH,
-		)
-		helmDownloadTarGzPath := fmt.Sprintf("%s/helm.tar.gz", toolsDir)
-		err = downloadManager.DownloadFile(helmDownloadTarGzPath, helmDownloadUrl)
-		if err != nil {
-			progress.Error(fmt.Sprintf("error while trying to download helm: %s", err))
-		}
-		helmTarDownload, err := os.Open(helmDownloadTarGzPath)
-		if err != nil {
-			progress.Error(fmt.Sprintf("could not read helm download content"))
-
-		}
-		downloadManager.ExtractFileFromTarGz(
-			helmTarDownload,
-			fmt.Sprintf("%s-%s/helm", k3d.LocalhostOS, k3d.LocalhostARCH),
-			helmClient,
-		)
-		err = os.Chmod(helmClient, 0755)
-		if err != nil {
-			log.Fatal().Msg(err.Error())
-		}
-		os.Remove(helmDownloadTarGzPath)
-	} else {
-		log.Info().Msg("helm is already installed, continuing")
-	}
-
-	// Download mkcert
-	mkcertClient := fmt.Sprintf("%s/mkcert", toolsDir)
-	_, err = os.Stat(mkcertClient)
-	if err != nil {
-		log.Info().Msg("Downloading mkcert...")
-		mkcertDownloadURL := fmt.Sprintf(
-			"https://github.com/FiloSottile/mkcert/releases/download/%s/mkcert-%s-%s-%s",
-			"v1.4.4",
-			"v1.4.4",
-			k3d.LocalhostOS,
-			k3d.LocalhostARCH,
-		)
-		err = downloadManager.DownloadFile(mkcertClient, mkcertDownloadURL)
-		if err != nil {
-			progress.Error(fmt.Sprintf("error while trying to download mkcert: %s", err))
+		if toolsBundle != nil {
+			err = toolsBundle.InstallTool(tool, toolsDir)
+		} else {
+			err = tool.Download(toolsDir)
 		}
-		err = os.Chmod(mkcertClient, 0755)
 		if err != nil {
 			progress.Error(err.Error())
+			return
 		}
-	} else {
-		log.Info().Msg("mkcert is already installed, continuing")
 	}
+	k3dClient := tools.K3d.Path(toolsDir)
+	mkcertClient := tools.Mkcert.Path(toolsDir)
+
+	progress.CompleteStep("Download k3d")
 
 	progress.AddStep("Create k3d cluster")
 
@@ -263,84 +223,16 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 	// Establish Kubernetes client for console cluster
 	kcfg := k8s.CreateKubeConfig(false, kubeconfigPath)
 
-	// Determine if helm chart repository has already been added
-	res, _, err := pkg.ExecShellReturnStrings(
-		helmClient,
-		"repo",
-		"list",
-		"-o",
-		"yaml",
-	)
-	if err != nil {
-		log.Fatal().Msgf("error listing current helm repositories: %s", err)
-	}
-
-	var existingHelmRepositories []helm.HelmRepo
-	repoExists := false
-
-	err = yaml.Unmarshal([]byte(res), &existingHelmRepositories)
-	if err != nil {
-		progress.Error(fmt.Sprintf("could not get existing helm repositories: %s", err))
-	}
-	for _, repo := range existingHelmRepositories {
-		if repo.Name == helmChartRepoName && repo.URL == helmChartRepoURL {
-			repoExists = true
-		}
-	}
-
-	if !repoExists {
-		// Add helm chart repository
-		_, _, err = pkg.ExecShellReturnStrings(
-			helmClient,
-			"repo",
-			"add",
-			helmChartRepoName,
-			helmChartRepoURL,
-		)
-		if err != nil {
-			log.Error().Msgf("error adding helm chart repository: %s", err)
-		}
-		log.Info().Msg("Added Kubefirst helm chart repository")
-	} else {
-		log.Info().Msg("Kubefirst helm chart repository already added")
-	}
-
-	// Update helm chart repository locally
-	_, _, err = pkg.ExecShellReturnStrings(
-		helmClient,
-		"repo",
-		"update",
-	)
-	if err != nil {
-		log.Error().Msgf("error updating helm chart repository: %s", err)
-	}
-	log.Info().Msg("Kubefirst helm chart repository updated")
+	helmClient := helm.NewClient(kubeconfigPath)
 
 	// Determine if helm release has already been installed
-	res, _, err = pkg.ExecShellReturnStrings(
-		helmClient,
-		"--kubeconfig",
-		kubeconfigPath,
-		"list",
-		"-o",
-		"yaml",
-		"-A",
-	)
-	if err != nil {
-		log.Error().Msgf("error listing current helm repositories: %s", err)
-	}
-
-	var existingHelmReleases []helm.HelmRelease
-	chartInstalled := false
-
-	err = yaml.Unmarshal([]byte(res), &existingHelmReleases)
-	if err != nil {
+	chartInstalled := true
+	_, err = helmClient.Status(helmChartName, namespace)
+	if errors.Is(err, helm.ErrReleaseNotFound) {
+		chartInstalled = false
+	} else if err != nil {
 		progress.Error(fmt.Sprintf("could not get existing helm releases: %s", err))
-	}
-	for _, release := range existingHelmReleases {
-		if release.Name == helmChartName {
-			chartInstalled = true
-		}
+		return
 	}
 
 	kubefirstTeam := os.Getenv("KUBEFIRST_TEAM")
@@ -349,107 +241,74 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 	progress.AddStep("Installing Kubefirst")
 
 	if !chartInstalled {
-		installFlags := []string{
-			"install",
-			"--kubeconfig",
-			kubeconfigPath,
-			"--namespace",
-			namespace,
-			helmChartName,
-			"--version",
-			helmChartVersion,
-			"kubefirst/kubefirst",
-			"--set",
-			"console.ingress.createTraefikRoute=true",
-			"--set",
-			fmt.Sprintf("global.kubefirstVersion=%s", configs.K1Version),
-			"--set",
-			"global.cloudProvider=k3d",
-			"--set",
-			"global.clusterType=bootstrap",
-			"--set",
-			"global.domainName=kubefirst.dev",
-			"--set",
-			"global.installMethod=kubefirst-launch",
-			"--set",
-			"global.kubefirstClient=cli",
-			"--set",
-			fmt.Sprintf("global.kubefirstTeam=%s", kubefirstTeam),
-			"--set",
-			fmt.Sprintf("global.kubefirstTeamInfo=%s", kubefirstTeamInfo),
-			"--set",
-			fmt.Sprintf("global.useTelemetry=%s", strconv.FormatBool(useTelemetry)),
+		chart := helm.Chart{
+			Name:    helmChartName,
+			RepoURL: helmChartRepoURL,
+			Version: chartVersion,
 		}
-
-		if len(additionalHelmFlags) > 0 {
-			for _, f := range additionalHelmFlags {
-				installFlags = append(installFlags, "--set")
-				installFlags = append(installFlags, f)
-			}
+		if toolsBundle != nil {
+			chart.Path = toolsBundle.ChartPath()
 		}
 
+		values := helm.Values{}
+		values.Set("console.ingress.createTraefikRoute", true)
+		values.Set("global.kubefirstVersion", configs.K1Version)
+		values.Set("global.cloudProvider", "k3d")
+		values.Set("global.clusterType", "bootstrap")
+		values.Set("global.domainName", "kubefirst.dev")
+		values.Set("global.installMethod", "kubefirst-launch")
+		values.Set("global.kubefirstClient", "cli")
+		values.Set("global.kubefirstTeam", kubefirstTeam)
+		values.Set("global.kubefirstTeamInfo", kubefirstTeamInfo)
+		values.Set("global.useTelemetry", options.UseTelemetry)
+
+		installOptions := helm.Options{}
+
 		switch viper.GetString("launch.database-destination") {
 		case "in-cluster":
-			installFlags = append(installFlags, "--create-namespace")
-			installFlags = append(installFlags, "--set")
-			installFlags = append(installFlags, "mongodb.enabled=true")
+			installOptions.CreateNamespace = true
+			values.Set("mongodb.enabled", true)
 
 			if k3d.LocalhostARCH == "arm64" {
-				installFlags = append(installFlags, "--set")
-				installFlags = append(installFlags, "mongodb.image.repository=arm64v8/mongo,mongodb.image.tag=latest,mongodb.persistence.mountPath=/data/db,mongodb.extraEnvVarsSecret=kubefirst-initial-secrets")
+				values.Set("mongodb.image.repository", "arm64v8/mongo")
+				values.Set("mongodb.image.tag", "latest")
+				values.Set("mongodb.persistence.mountPath", "/data/db")
+				values.Set("mongodb.extraEnvVarsSecret", "kubefirst-initial-secrets")
 			}
-		case "atlas":
-			installFlags = append(installFlags, "--set")
-			installFlags = append(installFlags, "mongodb.enabled=false")
-			installFlags = append(installFlags, "--set")
-			installFlags = append(installFlags, fmt.Sprintf("kubefirst-api.existingSecret=%s", secretName))
-			installFlags = append(installFlags, "--set")
-			installFlags = append(installFlags, fmt.Sprintf("kubefirst-api.atlasDbHost=%s", dbHost))
-			installFlags = append(installFlags, "--set")
-			installFlags = append(installFlags, fmt.Sprintf("kubefirst-api.atlasDbUsername=%s", dbUser))
-
-			// Create Namespace
-			_, err = kcfg.Clientset.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
-			if err == nil {
-				log.Info().Msg("kubernetes Namespace already created - skipping")
-			} else if strings.Contains(err.Error(), "not found") {
-				_, err = kcfg.Clientset.CoreV1().Namespaces().Create(context.Background(), &v1.Namespace{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: namespace,
-					},
-				}, metav1.CreateOptions{})
-				if err != nil {
-					progress.Error(fmt.Sprintf("error creating kubernetes secret for initial secret: %s", err))
-				}
-				log.Info().Msg("Created Kubernetes Namespace for kubefirst")
+		case "atlas", "external":
+			// a rerun that did not resolve the database installs with the stored credentials
+			// and leaves the secret alone
+			if consoleDatabase.destination == "" {
+				consoleDatabase, err = readDatabaseSecret(kcfg, viper.GetString("launch.database-destination"))
+			} else {
+				err = createDatabaseSecret(kcfg, consoleDatabase)
+			}
+			if err != nil {
+				progress.Error(err.Error())
+				return
 			}
 
-			// Create Secret
-			_, err = kcfg.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), secretName, metav1.GetOptions{})
-			if err == nil {
-				log.Info().Msg(fmt.Sprintf("kubernetes secret %s/%s already created - skipping", namespace, secretName))
-			} else if strings.Contains(err.Error(), "not found") {
-				_, err = kcfg.Clientset.CoreV1().Secrets(namespace).Create(context.Background(), &v1.Secret{
-					Type: "Opaque",
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      secretName,
-						Namespace: namespace,
-					},
-					Data: map[string][]byte{
-						"mongodb-root-password": []byte(dbPassword),
-					},
-				}, metav1.CreateOptions{})
-				if err != nil {
-					progress.Error(fmt.Sprintf("error creating kubernetes secret for initial secret: %s", err))
-				}
-				log.Info().Msg("Created Kubernetes Secret for database authentication")
+			values.Set("mongodb.enabled", false)
+			values.Set("kubefirst-api.existingSecret", secretName)
+			// the api connects to atlas hosts with mongodb+srv:// and to local hosts with mongodb://
+			if consoleDatabase.hostType == "atlas" {
+				values.Set("kubefirst-api.atlasDbHost", consoleDatabase.host)
+				values.Set("kubefirst-api.atlasDbUsername", consoleDatabase.user)
+			} else {
+				values.Set("kubefirst-api.mongodbHost", consoleDatabase.host)
+				values.Set("kubefirst-api.mongodbUsername", consoleDatabase.user)
 			}
 		}
 
+		// Profile values, values files and --helm-flag override everything set above
+		values.Merge(profile.Values)
+		values.Merge(overrides)
+
 		// Install helm chart
-		a, b, err := pkg.ExecShellReturnStrings(helmClient, installFlags...)
+		_, err = helmClient.Install(helmChartName, namespace, chart, values, installOptions)
 		if err != nil {
-			progress.Error(fmt.Sprintf("error installing helm chart: %s %s %s", err, a, b))
+			progress.Error(fmt.Sprintf("error installing helm chart: %s", err))
+			return
 		}
 
 		log.Info().Msg("Kubefirst console helm chart installed successfully")
@@ -462,20 +321,9 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 	progress.AddStep("Waiting for kubefirst Deployment")
 
 	// Wait for API Deployment Pods to transition to Running
-	log.Info().Msg("Waiting for Kubefirst API Deployment...")
-	apiDeployment, err := k8s.ReturnDeploymentObject(
-		kcfg.Clientset,
-		"app.kubernetes.io/name",
-		"kubefirst-api",
-		"kubefirst",
-		240,
-	)
-	if err != nil {
-		progress.Error(fmt.Sprintf("error looking for kubefirst api: %s", err))
-	}
-	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, apiDeployment, 300)
+	err = waitForAPIDeployment(kcfg)
 	if err != nil {
-		progress.Error(fmt.Sprintf("error waiting for kubefirst api: %s", err))
+		progress.Error(err.Error())
 	}
 
 	// Generate certificate for console
@@ -547,7 +395,7 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 	progress.CompleteStep("Waiting for kubefirst Deployment")
 
 	if !inCluster {
-		log.Info().Msg(fmt.Sprintf("Kubefirst Console is now available! %s", consoleURL))
+		log.Info().Msg(fmt.Sprintf("Kubefirst Console is now available! %s", cluster.DefaultConsoleURL))
 
 		log.Warn().Msgf("Kubefirst has generated local certificates for use with the console using `mkcert`.")
 		log.Warn().Msgf("If you experience certificate errors when accessing the console, please run the following command: ")
@@ -558,13 +406,14 @@ func Up(additionalHelmFlags []string, inCluster bool, useTelemetry bool) {
 		log.Info().Msg("To remove Kubefirst Console and the k3d cluster it runs in, please run the following command: ")
 		log.Info().Msg("kubefirst launch down")
 
-		err = pkg.OpenBrowser(consoleURL)
+		err = pkg.OpenBrowser(cluster.DefaultConsoleURL)
 		if err != nil {
 			log.Error().Msgf("error attempting to open console in browser: %s", err)
 		}
 	}
 
 	viper.Set("launch.deployed", true)
+	viper.Set("launch.chart-version", chartVersion)
 	viper.WriteConfig()
 
 	if !inCluster {
@@ -621,28 +470,28 @@ func Down(inCluster bool) {
 	}
 }
 
-// ListClusters makes a request to the console API to list created clusters
-func ListClusters() {
-	clusters, err := cluster.GetClusters()
-
-	err = displayFormattedClusterInfo(clusters)
+// ListClusters makes a request to the console API to list created clusters matching the filter
+func ListClusters(filter ClusterFilter) {
+	consoleClient, err := cluster.NewDefaultConsoleClient()
 	if err != nil {
-		progress.Error(fmt.Sprintf("error printing cluster list: %s", err))
+		progress.Error(err.Error())
+		return
 	}
-}
 
-// DeleteCluster makes a request to the console API to delete a single cluster
-func DeleteCluster(managedClusterName string) {
-	err := cluster.DeleteCluster(managedClusterName)
+	clusters, err := consoleClient.GetClusters(context.Background())
+	if err != nil {
+		progress.Error(fmt.Sprintf("error listing clusters: %s", err))
+		return
+	}
 
+	clusters, err = filterClusters(clusters, filter)
 	if err != nil {
-		progress.Error(fmt.Sprintf("error: cluster %s not found\n", managedClusterName))
+		progress.Error(err.Error())
+		return
 	}
 
-	deleteMessage := `
-##
-### Submitted request to delete cluster` + fmt.Sprintf("`%s`", managedClusterName) + `
-### :bulb: - follow progress with ` + fmt.Sprintf("`%s`", "kubefirst launch cluster list") + `
-`
-	progress.Success(deleteMessage)
+	err = displayFormattedClusterInfo(clusters)
+	if err != nil {
+		progress.Error(fmt.Sprintf("error printing cluster list: %s", err))
+	}
 }
@@ -7,11 +7,9 @@ See the LICENSE file for more details.
 package launch
 
 const (
-	consoleURL        = "https://console.kubefirst.dev"
-	helmChartName     = "kubefirst"
-	helmChartRepoName = "kubefirst"
-	helmChartRepoURL  = "https://charts.kubefirst.com"
-	helmChartVersion  = "2.3.5"
-	namespace         = "kubefirst"
-	secretName        = "kubefirst-initial-secrets"
+	helmChartName    = "kubefirst"
+	helmChartRepoURL = "https://charts.kubefirst.com"
+	helmChartVersion = "2.3.5"
+	namespace        = "kubefirst"
+	secretName       = "kubefirst-initial-secrets"
 )
@@ -0,0 +1,200 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/term"
+)
+
+const (
+	// DefaultDeleteTimeout bounds how long launch cluster delete waits for the cluster to be gone
+	DefaultDeleteTimeout = 45 * time.Minute
+
+	// deletePollInterval matches the interval the create flow polls the cluster record with
+	deletePollInterval = 10 * time.Second
+)
+
+// DeleteOptions control the confirmation and the wait of launch cluster delete
+type DeleteOptions struct {
+	// Yes skips the confirmation prompt
+	Yes bool
+	// Wait polls the cluster record until the cluster is gone or failed to delete
+	Wait    bool
+	Timeout time.Duration
+}
+
+// deleteResult is the structured form of launch cluster delete
+type deleteResult struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+}
+
+// DeleteCluster shows what a cluster deletion destroys, asks for confirmation, makes a
+// request to the console API to delete the cluster and waits until the cluster is gone
+func DeleteCluster(managedClusterName string, options DeleteOptions) {
+	consoleClient, err := cluster.NewDefaultConsoleClient()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	managedCluster, err := consoleClient.GetCluster(context.Background(), managedClusterName)
+	if cluster.IsNotFound(err) {
+		progress.Error(fmt.Sprintf("error: cluster %s not found\n", managedClusterName))
+		return
+	}
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	// a deletion that is already running is only waited for
+	if managedCluster.Status != "deleting" {
+		if !options.Yes {
+			err = confirmDeletion(managedCluster)
+			if err != nil {
+				progress.Error(err.Error())
+				return
+			}
+		}
+
+		progress.AddStep(fmt.Sprintf("Submitting delete request for %s", managedClusterName))
+
+		err = consoleClient.DeleteCluster(context.Background(), managedClusterName)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
+
+		progress.CompleteStep(fmt.Sprintf("Submitting delete request for %s", managedClusterName))
+	}
+
+	if !options.Wait {
+		if progress.IsStructuredOutput() {
+			displayDeleteResult(deleteResult{Name: managedClusterName, Status: "deleting"})
+			return
+		}
+
+		deleteMessage := `
+##
+### Submitted request to delete cluster` + fmt.Sprintf("`%s`", managedClusterName) + `
+### :bulb: - follow progress with ` + fmt.Sprintf("`%s`", "kubefirst launch cluster list") + `
+`
+		progress.Success(deleteMessage)
+		return
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDeleteTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := waitForClusterDeletion(ctx, consoleClient, managedClusterName, managedCluster.Status)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	displayDeleteResult(result)
+}
+
+// confirmDeletion prints what deleting the cluster destroys and asks for the cluster name
+func confirmDeletion(managedCluster types.Cluster) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("deleting cluster %s needs confirmation, pass --yes when not running in a terminal", managedCluster.ClusterName)
+	}
+
+	git := managedCluster.GitProvider
+	if managedCluster.GitAuth.Owner != "" {
+		git = fmt.Sprintf("%s/%s", managedCluster.GitProvider, managedCluster.GitAuth.Owner)
+	}
+
+	fmt.Printf("\nDeleting cluster %s destroys:\n", managedCluster.ClusterName)
+	fmt.Printf("  - the %s cluster and its cloud resources on %s in %s\n", managedCluster.ClusterType, managedCluster.CloudProvider, managedCluster.CloudRegion)
+	fmt.Printf("  - the dns records kubefirst created in %s\n", clusterDomain(managedCluster))
+	fmt.Printf("  - the git repositories and teams kubefirst created in %s\n", git)
+	fmt.Printf("\nType the cluster name to confirm: ")
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("error reading confirmation: %s", err)
+	}
+	if strings.TrimSpace(answer) != managedCluster.ClusterName {
+		return fmt.Errorf("deletion of cluster %s was not confirmed", managedCluster.ClusterName)
+	}
+
+	return nil
+}
+
+// waitForClusterDeletion polls the cluster record until it is gone, reports an error when
+// the cluster fails to delete or the context expires. A cluster that failed to provision
+// keeps its error status until the api picks up the deletion, so that status only counts
+// once the cluster has been deleting
+func waitForClusterDeletion(ctx context.Context, consoleClient *cluster.ConsoleClient, managedClusterName string, initialStatus string) (deleteResult, error) {
+	step := fmt.Sprintf("Deleting cluster %s", managedClusterName)
+	progress.AddStep(step)
+
+	deleting := initialStatus != "error"
+	lastCondition := ""
+	for {
+		managedCluster, err := consoleClient.GetCluster(ctx, managedClusterName)
+		switch {
+		case cluster.IsNotFound(err):
+			progress.CompleteStep(step)
+			return deleteResult{Name: managedClusterName, Status: "deleted"}, nil
+		case err != nil && ctx.Err() == nil:
+			log.Warn().Msgf("unable to poll cluster %s, retrying: %s", managedClusterName, err)
+		case err == nil:
+			if managedCluster.Status == "deleting" {
+				deleting = true
+			}
+			if managedCluster.Status == "error" && deleting {
+				return deleteResult{}, fmt.Errorf("cluster %s failed to delete: %s", managedClusterName, managedCluster.LastCondition)
+			}
+			if managedCluster.LastCondition != "" && managedCluster.LastCondition != lastCondition {
+				lastCondition = managedCluster.LastCondition
+				log.Info().Msgf("cluster %s is %s: %s", managedClusterName, managedCluster.Status, lastCondition)
+				progress.AddStep(fmt.Sprintf("%s - %s", step, lastCondition))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return deleteResult{}, fmt.Errorf("timed out waiting for cluster %s to be deleted, follow progress with `kubefirst launch cluster describe %s`", managedClusterName, managedClusterName)
+		case <-time.After(deletePollInterval):
+		}
+	}
+}
+
+// displayDeleteResult reports the state of a deleted cluster
+func displayDeleteResult(result deleteResult) {
+	if progress.IsStructuredOutput() {
+		err := progress.Document(result)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	progress.Success(fmt.Sprintf(`
+##
+### :wastebasket: Cluster `+"`%s`"+` was deleted
+`, result.Name))
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"gopkg.in/yaml.v2"
+)
+
+// redacted replaces the value of every credential in a described cluster
+const redacted = "REDACTED"
+
+// sensitiveKeys are the parts of a field name that mark it as a credential
+var sensitiveKeys = []string{"token", "password", "secret", "private_key", "privatekey", "key_file", "access_key", "api_key", "spaces_key", "issuer_key"}
+
+// clusterDescription is the structured form of launch cluster describe
+type clusterDescription struct {
+	Name               string                       `json:"name" yaml:"name"`
+	Status             string                       `json:"status" yaml:"status"`
+	LastCondition      string                       `json:"last_condition" yaml:"last_condition"`
+	ProvisioningChecks []progress.ProvisioningCheck `json:"provisioning_checks" yaml:"provisioning_checks"`
+	Record             map[string]interface{}       `json:"record" yaml:"record"`
+}
+
+// DescribeCluster makes a request to the console API to print a single cluster record with
+// its credentials redacted and the state of every provisioning step
+func DescribeCluster(managedClusterName string) {
+	consoleClient, err := cluster.NewDefaultConsoleClient()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	managedCluster, err := consoleClient.GetCluster(context.Background(), managedClusterName)
+	if cluster.IsNotFound(err) {
+		progress.Error(fmt.Sprintf("error: cluster %s not found\n", managedClusterName))
+		return
+	}
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	record, err := redactCluster(managedCluster)
+	if err != nil {
+		progress.Error(fmt.Sprintf("error reading cluster %s: %s", managedClusterName, err))
+		return
+	}
+
+	description := clusterDescription{
+		Name:               managedCluster.ClusterName,
+		Status:             managedCluster.Status,
+		LastCondition:      managedCluster.LastCondition,
+		ProvisioningChecks: progress.ProvisioningChecks(managedCluster),
+		Record:             record,
+	}
+
+	if progress.IsStructuredOutput() {
+		err = progress.Document(description)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	err = displayClusterDescription(managedCluster, description)
+	if err != nil {
+		progress.Error(fmt.Sprintf("error printing cluster %s: %s", managedClusterName, err))
+	}
+}
+
+// redactCluster returns the cluster record as a map with every credential replaced
+func redactCluster(managedCluster types.Cluster) (map[string]interface{}, error) {
+	content, err := json.Marshal(managedCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	record := map[string]interface{}{}
+	err = json.Unmarshal(content, &record)
+	if err != nil {
+		return nil, err
+	}
+	redactValues(record)
+
+	return record, nil
+}
+
+// redactValues walks a decoded json document and replaces every non empty string stored
+// under a sensitive key, flags such as cluster_secrets_created_check are kept
+func redactValues(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if text, ok := nested.(string); ok {
+				if text != "" && isSensitiveKey(key) {
+					typed[key] = redacted
+				}
+				continue
+			}
+			redactValues(nested)
+		}
+	case []interface{}:
+		for _, nested := range typed {
+			redactValues(nested)
+		}
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// displayClusterDescription prints an overview, the provisioning steps and the redacted record
+func displayClusterDescription(managedCluster types.Cluster, description clusterDescription) error {
+	record, err := yaml.Marshal(description.Record)
+	if err != nil {
+		return err
+	}
+
+	git := managedCluster.GitProvider
+	if managedCluster.GitAuth.Owner != "" {
+		git = fmt.Sprintf("%s/%s", managedCluster.GitProvider, managedCluster.GitAuth.Owner)
+	}
+
+	content := fmt.Sprintf(`
+### Cluster `+"`%s`"+`
+
+| | |
+| --- | --- |
+| status | %s |
+| type | %s |
+| created at | %s |
+| provider | %s |
+| region | %s |
+| domain | %s |
+| git | %s |
+| last condition | %s |
+
+### Provisioning
+
+`,
+		description.Name,
+		description.Status,
+		managedCluster.ClusterType,
+		managedCluster.CreationTimestamp,
+		managedCluster.CloudProvider,
+		managedCluster.CloudRegion,
+		clusterDomain(managedCluster),
+		git,
+		strings.ReplaceAll(description.LastCondition, "|", "/"),
+	)
+	for _, check := range description.ProvisioningChecks {
+		icon := ":white_large_square:"
+		if check.Passed {
+			icon = ":white_check_mark:"
+		}
+		content = content + fmt.Sprintf("- %s %s\n", icon, check.Label)
+	}
+	content = content + fmt.Sprintf("\n### Record\n\n```yaml\n%s```\n", record)
+
+	progress.Success(content)
+
+	return nil
+}
@@ -0,0 +1,292 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"gopkg.in/yaml.v2"
+)
+
+// profileNamePattern keeps profile names usable as file names
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Profile is a named console setup stored in ~/.k1/profiles so the same console can be
+// launched anywhere with `kubefirst launch up --profile <name>`
+type Profile struct {
+	Name string `json:"name" yaml:"-"`
+	// ChartVersion is the console chart version, empty for the version this kubefirst installs
+	ChartVersion string `json:"chart_version,omitempty" yaml:"chartVersion,omitempty"`
+	// DatabaseDestination skips the database prompt, in-cluster, atlas or external
+	DatabaseDestination string `json:"database_destination,omitempty" yaml:"databaseDestination,omitempty"`
+	// Values are applied on top of the values launch up sets
+	Values helm.Values `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// profilesDir returns the directory profiles are stored in
+func profilesDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("something went wrong getting home path: %s", err)
+	}
+
+	return filepath.Join(homeDir, ".k1", "profiles"), nil
+}
+
+func profilePath(name string) (string, error) {
+	if !profileNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q, use lowercase letters, numbers and dashes", name)
+	}
+
+	dir, err := profilesDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s.yaml", name)), nil
+}
+
+// validateDatabaseDestination checks a database destination set by a profile or a flag
+func validateDatabaseDestination(destination string) error {
+	switch destination {
+	case "", "in-cluster", "atlas", "external":
+		return nil
+	default:
+		return fmt.Errorf("%s is not a valid database destination, must be in-cluster, atlas or external", destination)
+	}
+}
+
+// LoadProfile reads a stored profile
+func LoadProfile(name string) (*Profile, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("launch profile %s does not exist, create it with `kubefirst launch profile create %s`", name, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{}
+	err = yaml.Unmarshal(content, profile)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse launch profile %s: %s", path, err)
+	}
+	profile.Name = name
+
+	err = validateDatabaseDestination(profile.DatabaseDestination)
+	if err != nil {
+		return nil, fmt.Errorf("launch profile %s: %s", name, err)
+	}
+
+	return profile, nil
+}
+
+// SaveProfile stores a profile, replacing one with the same name
+func SaveProfile(profile Profile) error {
+	path, err := profilePath(profile.Name)
+	if err != nil {
+		return err
+	}
+	err = validateDatabaseDestination(profile.DatabaseDestination)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("could not render launch profile %s: %s", profile.Name, err)
+	}
+
+	return os.WriteFile(path, content, 0600)
+}
+
+// ListProfiles returns every stored profile sorted by name
+func ListProfiles() ([]Profile, error) {
+	dir, err := profilesDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Profile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []Profile{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+
+		profile, err := LoadProfile(strings.TrimSuffix(entry.Name(), ".yaml"))
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+}
+
+// DeleteProfile removes a stored profile
+func DeleteProfile(name string) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("launch profile %s does not exist", name)
+	}
+
+	return err
+}
+
+// CreateProfile stores a profile built from launch up style flags, values files are read
+// into the profile so it does not depend on them afterwards
+func CreateProfile(name string, chartVersion string, databaseDestination string, valuesFiles []string, additionalHelmFlags []string) {
+	values, err := userValues(valuesFiles, additionalHelmFlags)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	profile := Profile{
+		Name:                name,
+		ChartVersion:        chartVersion,
+		DatabaseDestination: databaseDestination,
+		Values:              values,
+	}
+	err = SaveProfile(profile)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	displayProfile(profile, fmt.Sprintf("### :floppy_disk: Saved launch profile `%s`", name))
+}
+
+// ShowProfile prints a stored profile
+func ShowProfile(name string) {
+	profile, err := LoadProfile(name)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	displayProfile(*profile, fmt.Sprintf("### Launch profile `%s`", name))
+}
+
+// RemoveProfile deletes a stored profile
+func RemoveProfile(name string) {
+	err := DeleteProfile(name)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	progress.Success(fmt.Sprintf("Deleted launch profile `%s`", name))
+}
+
+// ListProfilesTable prints the stored profiles
+func ListProfilesTable() {
+	profiles, err := ListProfiles()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	if progress.IsStructuredOutput() {
+		err = progress.Document(profiles)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	content := `
+| NAME | CHART VERSION | DATABASE | VALUES |
+| --- | --- | --- | --- |
+`
+	for _, profile := range profiles {
+		chartVersion := profile.ChartVersion
+		if chartVersion == "" {
+			chartVersion = helmChartVersion
+		}
+		databaseDestination := profile.DatabaseDestination
+		if databaseDestination == "" {
+			databaseDestination = "prompt"
+		}
+		content = content + fmt.Sprintf("|%s|%s|%s|%d|\n", profile.Name, chartVersion, databaseDestination, len(profile.Values))
+	}
+
+	progress.Success(content)
+}
+
+func displayProfile(profile Profile, title string) {
+	if progress.IsStructuredOutput() {
+		err := progress.Document(profile)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	content, err := yaml.Marshal(profile)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	progress.Success(fmt.Sprintf("%s\n\n```yaml\n%s```\n", title, content))
+}
+
+// userValues merges values files in order and then --helm-flag expressions
+func userValues(valuesFiles []string, additionalHelmFlags []string) (helm.Values, error) {
+	values := helm.Values{}
+
+	for _, valuesFile := range valuesFiles {
+		fileValues, err := helm.ReadValuesFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		values.Merge(fileValues)
+	}
+
+	for _, f := range additionalHelmFlags {
+		additionalValues, err := helm.ParseSet(f)
+		if err != nil {
+			return nil, err
+		}
+		values.Merge(additionalValues)
+	}
+
+	return values, nil
+}
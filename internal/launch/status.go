@@ -0,0 +1,359 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/kubefirst/internal/tools"
+	"github.com/kubefirst/runtime/pkg"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+
+	// StatusExitWarn is the exit code of launch status when a check warns and none fails,
+	// a failed check exits with 1
+	StatusExitWarn = 2
+
+	// certificateExpiryWarning is how long before its expiry the console certificate is reported
+	certificateExpiryWarning = 30 * 24 * time.Hour
+	// healthTimeout bounds the request to the console health endpoint
+	healthTimeout = 10 * time.Second
+)
+
+// healthCheck is the result of a single launch status check
+type healthCheck struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Detail string `json:"detail" yaml:"detail"`
+}
+
+// statusReport is the structured form of launch status
+type statusReport struct {
+	Cluster string        `json:"cluster" yaml:"cluster"`
+	Status  string        `json:"status" yaml:"status"`
+	Checks  []healthCheck `json:"checks" yaml:"checks"`
+}
+
+// k3dCluster is the part of `k3d cluster get --output json` launch status reads
+type k3dCluster struct {
+	Name           string `json:"name"`
+	ServersCount   int    `json:"serversCount"`
+	ServersRunning int    `json:"serversRunning"`
+}
+
+// Status reports the health of the console cluster, its helm release, the kubefirst api,
+// database and ingress, the console certificate and the console api, and exits with 1
+// when a check fails or StatusExitWarn when a check warns
+func Status() {
+	if !viper.GetBool("launch.deployed") {
+		progress.Error("Kubefirst console has not been deployed. Run `kubefirst launch up` to deploy it.")
+		return
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		progress.Error(fmt.Sprintf("something went wrong getting home path: %s", err))
+		return
+	}
+	dir := fmt.Sprintf("%s/.k1/%s", homeDir, consoleClusterName)
+	toolsDir := fmt.Sprintf("%s/tools", dir)
+	kubeconfigPath := fmt.Sprintf("%s/.k1/%s/kubeconfig", homeDir, consoleClusterName)
+
+	report := statusReport{
+		Cluster: consoleClusterName,
+		Checks:  []healthCheck{},
+	}
+
+	clusterCheck := checkK3dCluster(toolsDir, kubeconfigPath)
+	report.Checks = append(report.Checks, clusterCheck)
+
+	clusterChecks := []string{"helm release", "kubefirst-api", "traefik", "tls certificate"}
+	if viper.GetString("launch.database-destination") == "in-cluster" {
+		clusterChecks = []string{"helm release", "kubefirst-api", "mongodb", "traefik", "tls certificate"}
+	}
+	if clusterCheck.Status == checkFail {
+		for _, name := range clusterChecks {
+			report.Checks = append(report.Checks, healthCheck{
+				Name:   name,
+				Status: checkFail,
+				Detail: "not checked, the k3d cluster is not available",
+			})
+		}
+	} else {
+		kcfg := k8s.CreateKubeConfig(false, kubeconfigPath)
+		helmClient := helm.NewClient(kubeconfigPath)
+
+		for _, name := range clusterChecks {
+			switch name {
+			case "helm release":
+				report.Checks = append(report.Checks, checkRelease(helmClient))
+			case "kubefirst-api":
+				report.Checks = append(report.Checks, checkWorkload(kcfg, name, namespace, "kubefirst-api"))
+			case "mongodb":
+				report.Checks = append(report.Checks, checkWorkload(kcfg, name, namespace, "mongodb"))
+			case "traefik":
+				report.Checks = append(report.Checks, checkWorkload(kcfg, name, "kube-system", "traefik"))
+			case "tls certificate":
+				report.Checks = append(report.Checks, checkCertificate(kcfg))
+			}
+		}
+	}
+
+	report.Checks = append(report.Checks, checkConsoleHealth(toolsDir))
+
+	report.Status = checkPass
+	for _, check := range report.Checks {
+		if check.Status == checkFail {
+			report.Status = checkFail
+			break
+		}
+		if check.Status == checkWarn {
+			report.Status = checkWarn
+		}
+	}
+
+	switch report.Status {
+	case checkFail:
+		progress.SetExitCode(1)
+	case checkWarn:
+		progress.SetExitCode(StatusExitWarn)
+	}
+
+	displayStatusReport(report)
+}
+
+// checkK3dCluster reports whether the console k3d cluster exists and its servers are running
+func checkK3dCluster(toolsDir string, kubeconfigPath string) healthCheck {
+	check := healthCheck{Name: "k3d cluster"}
+
+	if !tools.K3d.Installed(toolsDir) {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("k3d is not installed in %s", toolsDir)
+		return check
+	}
+	if _, err := os.Stat(kubeconfigPath); err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("kubeconfig %s is missing", kubeconfigPath)
+		return check
+	}
+
+	out, _, err := pkg.ExecShellReturnStrings(tools.K3d.Path(toolsDir), "cluster", "get", consoleClusterName, "--output", "json")
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("cluster %s does not exist", consoleClusterName)
+		return check
+	}
+
+	clusters := []k3dCluster{}
+	err = json.Unmarshal([]byte(out), &clusters)
+	if err != nil || len(clusters) == 0 {
+		log.Warn().Msgf("could not read k3d cluster %s: %s", consoleClusterName, err)
+		check.Status = checkWarn
+		check.Detail = "cluster exists but its servers could not be read"
+		return check
+	}
+
+	k3dCluster := clusters[0]
+	check.Detail = fmt.Sprintf("%d/%d servers running", k3dCluster.ServersRunning, k3dCluster.ServersCount)
+	switch {
+	case k3dCluster.ServersRunning == 0:
+		check.Status = checkFail
+	case k3dCluster.ServersRunning < k3dCluster.ServersCount:
+		check.Status = checkWarn
+	default:
+		check.Status = checkPass
+	}
+
+	return check
+}
+
+// checkRelease reports the state of the console helm release
+func checkRelease(helmClient *helm.Client) healthCheck {
+	check := healthCheck{Name: "helm release"}
+
+	release, err := helmClient.Status(helmChartName, namespace)
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Detail = fmt.Sprintf("%s revision %s %s", release.Chart, release.Revision, release.Status)
+	switch release.Status {
+	case "deployed":
+		check.Status = checkPass
+	case "failed", "uninstalling", "uninstalled":
+		check.Status = checkFail
+	default:
+		// pending-install, pending-upgrade and pending-rollback settle on their own
+		check.Status = checkWarn
+	}
+
+	return check
+}
+
+// checkWorkload reports how many replicas of the deployment or statefulset labelled
+// app.kubernetes.io/name=appName are ready, without waiting for them
+func checkWorkload(kcfg *k8s.KubernetesClient, name string, workloadNamespace string, appName string) healthCheck {
+	check := healthCheck{Name: name}
+	listOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s", appName)}
+
+	var ready, desired int32
+	deployments, err := kcfg.Clientset.AppsV1().Deployments(workloadNamespace).List(context.Background(), listOptions)
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("could not list deployments: %s", err)
+		return check
+	}
+	for _, deployment := range deployments.Items {
+		ready += deployment.Status.ReadyReplicas
+		desired += deployment.Status.Replicas
+	}
+	if len(deployments.Items) == 0 {
+		statefulSets, err := kcfg.Clientset.AppsV1().StatefulSets(workloadNamespace).List(context.Background(), listOptions)
+		if err != nil {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("could not list statefulsets: %s", err)
+			return check
+		}
+		if len(statefulSets.Items) == 0 {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("no %s workload found in namespace %s", appName, workloadNamespace)
+			return check
+		}
+		for _, statefulSet := range statefulSets.Items {
+			ready += statefulSet.Status.ReadyReplicas
+			desired += statefulSet.Status.Replicas
+		}
+	}
+
+	check.Detail = fmt.Sprintf("%d/%d replicas ready", ready, desired)
+	switch {
+	case ready == 0:
+		check.Status = checkFail
+	case ready < desired:
+		check.Status = checkWarn
+	default:
+		check.Status = checkPass
+	}
+
+	return check
+}
+
+// checkCertificate reports the expiry of the mkcert certificate the console is served with
+func checkCertificate(kcfg *k8s.KubernetesClient) healthCheck {
+	check := healthCheck{Name: "tls certificate"}
+
+	secret, err := kcfg.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), "kubefirst-console-tls", metav1.GetOptions{})
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("could not read secret kubefirst-console-tls: %s", err)
+		return check
+	}
+
+	block, _ := pem.Decode(secret.Data["tls.crt"])
+	if block == nil {
+		check.Status = checkFail
+		check.Detail = "secret kubefirst-console-tls holds no certificate"
+		return check
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("could not parse the certificate in kubefirst-console-tls: %s", err)
+		return check
+	}
+
+	remaining := time.Until(certificate.NotAfter)
+	check.Detail = fmt.Sprintf("expires %s", certificate.NotAfter.Format("2006-01-02"))
+	switch {
+	case remaining <= 0:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("expired %s", certificate.NotAfter.Format("2006-01-02"))
+	case remaining < certificateExpiryWarning:
+		check.Status = checkWarn
+	default:
+		check.Status = checkPass
+	}
+
+	return check
+}
+
+// checkConsoleHealth reports whether the console answers on its health endpoint
+func checkConsoleHealth(toolsDir string) healthCheck {
+	check := healthCheck{Name: "console api"}
+
+	consoleClient := cluster.NewConsoleClient(cluster.DefaultConsoleURL)
+	consoleClient.Timeout = healthTimeout
+	consoleClient.MaxRetries = 0
+
+	err := consoleClient.Health(context.Background())
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		if errors.As(err, &unknownAuthority) {
+			check.Status = checkWarn
+			check.Detail = fmt.Sprintf("%s responds but its certificate is not trusted, run `%s -install`", cluster.DefaultConsoleURL, tools.Mkcert.Path(toolsDir))
+			return check
+		}
+
+		check.Status = checkFail
+		check.Detail = err.Error()
+		return check
+	}
+
+	check.Status = checkPass
+	check.Detail = fmt.Sprintf("%s/api/proxyHealth responds", cluster.DefaultConsoleURL)
+
+	return check
+}
+
+// displayStatusReport prints the checks as a table
+func displayStatusReport(report statusReport) {
+	if progress.IsStructuredOutput() {
+		err := progress.Document(report)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	icons := map[string]string{
+		checkPass: ":white_check_mark:",
+		checkWarn: ":warning:",
+		checkFail: ":x:",
+	}
+
+	content := fmt.Sprintf(`
+### %s Kubefirst console cluster %s
+
+| CHECK | STATUS | DETAIL |
+| --- | --- | --- |
+`, icons[report.Status], report.Cluster)
+	for _, check := range report.Checks {
+		content = content + fmt.Sprintf("|%s|%s %s|%s|\n", check.Name, icons[check.Status], check.Status, check.Detail)
+	}
+
+	progress.Success(content)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubefirst/kubefirst/internal/helm"
+	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/runtime/configs"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// upgradeTimeout bounds how long helm waits for the upgraded release and its rollback
+const upgradeTimeout = 5 * time.Minute
+
+// releaseDiff is the structured form of an upgrade
+type releaseDiff struct {
+	Release         string `json:"release" yaml:"release"`
+	Namespace       string `json:"namespace" yaml:"namespace"`
+	CurrentVersion  string `json:"current_version" yaml:"current_version"`
+	TargetVersion   string `json:"target_version" yaml:"target_version"`
+	CurrentRevision string `json:"current_revision" yaml:"current_revision"`
+	TargetRevision  string `json:"target_revision,omitempty" yaml:"target_revision,omitempty"`
+	Status          string `json:"status" yaml:"status"`
+}
+
+// Upgrade moves the console helm release to another chart version in place, keeping the
+// values it was installed with so the database choice and in-cluster data survive, and
+// rolls back to the installed revision when the upgrade or the api rollout fails
+func Upgrade(targetVersion string) {
+	if !viper.GetBool("launch.deployed") {
+		progress.Error("Kubefirst console has not been deployed. Run `kubefirst launch up` to deploy it.")
+		return
+	}
+	if targetVersion == "" {
+		targetVersion = helmChartVersion
+	}
+
+	progress.DisplayLogHints(5)
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		progress.Error(fmt.Sprintf("something went wrong getting home path: %s", err))
+		return
+	}
+	kubeconfigPath := fmt.Sprintf("%s/.k1/%s/kubeconfig", homeDir, consoleClusterName)
+
+	progress.AddStep("Check installed release")
+
+	helmClient := helm.NewClient(kubeconfigPath)
+
+	current, err := helmClient.Status(helmChartName, namespace)
+	if err != nil {
+		progress.Error(fmt.Sprintf("could not find the kubefirst console release: %s", err))
+		return
+	}
+
+	diff := releaseDiff{
+		Release:         current.Name,
+		Namespace:       current.Namespace,
+		CurrentVersion:  chartVersion(*current),
+		TargetVersion:   targetVersion,
+		CurrentRevision: current.Revision,
+		Status:          "up to date",
+	}
+
+	progress.CompleteStep("Check installed release")
+
+	if diff.CurrentVersion == diff.TargetVersion {
+		displayReleaseDiff(diff)
+		return
+	}
+
+	log.Info().Msgf("upgrading %s from chart version %s to %s", current.Name, diff.CurrentVersion, diff.TargetVersion)
+	progress.AddStep(fmt.Sprintf("Upgrading Kubefirst to %s", targetVersion))
+
+	values := helm.Values{}
+	values.Set("global.kubefirstVersion", configs.K1Version)
+
+	kcfg := k8s.CreateKubeConfig(false, kubeconfigPath)
+	upgraded, err := helmClient.Upgrade(helmChartName, namespace, helm.Chart{
+		Name:    helmChartName,
+		RepoURL: helmChartRepoURL,
+		Version: targetVersion,
+	}, values, helm.Options{
+		ReuseValues: true,
+		Wait:        true,
+		Timeout:     upgradeTimeout,
+	})
+	if err == nil {
+		err = waitForAPIDeployment(kcfg)
+	}
+	if err != nil {
+		progress.Error(rollbackUpgrade(helmClient, *current, err))
+		return
+	}
+
+	// the tls secret is created by launch up, outside the release, and has to survive the upgrade
+	_, err = kcfg.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), "kubefirst-console-tls", metav1.GetOptions{})
+	if err != nil {
+		log.Warn().Msgf("kubefirst-console-tls secret is missing after the upgrade, run `kubefirst launch down` and `kubefirst launch up` to recreate it: %s", err)
+	}
+
+	progress.CompleteStep(fmt.Sprintf("Upgrading Kubefirst to %s", targetVersion))
+
+	viper.Set("launch.chart-version", targetVersion)
+	viper.WriteConfig()
+
+	diff.TargetRevision = upgraded.Revision
+	diff.Status = "upgraded"
+	displayReleaseDiff(diff)
+}
+
+// rollbackUpgrade returns the release to the revision it had before the upgrade and
+// describes what happened
+func rollbackUpgrade(helmClient *helm.Client, current helm.HelmRelease, upgradeErr error) string {
+	log.Error().Msgf("upgrade failed, rolling back to revision %s: %s", current.Revision, upgradeErr)
+
+	revision, err := strconv.Atoi(current.Revision)
+	if err != nil {
+		return fmt.Sprintf("upgrade failed and revision %q could not be rolled back to: %s", current.Revision, upgradeErr)
+	}
+
+	_, err = helmClient.Rollback(helmChartName, namespace, revision, helm.Options{
+		Wait:    true,
+		Timeout: upgradeTimeout,
+	})
+	if err != nil {
+		return fmt.Sprintf("upgrade failed: %s - rolling back to revision %d failed as well: %s", upgradeErr, revision, err)
+	}
+
+	return fmt.Sprintf("upgrade failed and was rolled back to chart version %s (revision %d): %s", chartVersion(current), revision, upgradeErr)
+}
+
+// chartVersion returns the version of the console chart a release runs
+func chartVersion(release helm.HelmRelease) string {
+	return strings.TrimPrefix(release.Chart, fmt.Sprintf("%s-", helmChartName))
+}
+
+// displayReleaseDiff reports the installed and target versions of the release
+func displayReleaseDiff(diff releaseDiff) {
+	if progress.IsStructuredOutput() {
+		err := progress.Document(diff)
+		if err != nil {
+			progress.Error(err.Error())
+		}
+		return
+	}
+
+	targetRevision := diff.TargetRevision
+	if targetRevision == "" {
+		targetRevision = diff.CurrentRevision
+	}
+
+	progress.Success(fmt.Sprintf(`
+### :arrow_up: Kubefirst console %s
+
+| | CURRENT | TARGET |
+| --- | --- | --- |
+| chart version | %s | %s |
+| revision | %s | %s |
+`, diff.Status, diff.CurrentVersion, diff.TargetVersion, diff.CurrentRevision, targetRevision))
+}
@@ -8,26 +8,152 @@ package launch
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/kubefirst/internal/progress"
+	"github.com/kubefirst/runtime/pkg/k8s"
+	"github.com/rs/zerolog/log"
 )
 
+// ClusterFilter narrows and orders the cluster list, empty fields match every cluster
+type ClusterFilter struct {
+	Statuses  []string
+	Providers []string
+	Types     []string
+	// SortBy is created or name
+	SortBy  string
+	Reverse bool
+}
+
+// clusterListItem is the structured form of a row in the cluster list
+type clusterListItem struct {
+	Name          string `json:"name" yaml:"name"`
+	CreatedAt     string `json:"created_at" yaml:"created_at"`
+	Status        string `json:"status" yaml:"status"`
+	Type          string `json:"type" yaml:"type"`
+	CloudProvider string `json:"cloud_provider" yaml:"cloud_provider"`
+	CloudRegion   string `json:"cloud_region" yaml:"cloud_region"`
+	Domain        string `json:"domain" yaml:"domain"`
+	GitProvider   string `json:"git_provider" yaml:"git_provider"`
+	GitOwner      string `json:"git_owner" yaml:"git_owner"`
+	LastCondition string `json:"last_condition" yaml:"last_condition"`
+}
+
+// lastConditionWidth truncates the last condition in the cluster table
+const lastConditionWidth = 60
+
+// filterClusters returns the clusters matching the filter in the requested order
+func filterClusters(clusters []types.Cluster, filter ClusterFilter) ([]types.Cluster, error) {
+	filtered := []types.Cluster{}
+	for _, cluster := range clusters {
+		if matchesAny(cluster.Status, filter.Statuses) &&
+			matchesAny(cluster.CloudProvider, filter.Providers) &&
+			matchesAny(cluster.ClusterType, filter.Types) {
+			filtered = append(filtered, cluster)
+		}
+	}
+
+	switch filter.SortBy {
+	case "", "created":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return creationTime(filtered[i]).Before(creationTime(filtered[j]))
+		})
+	case "name":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return filtered[i].ClusterName < filtered[j].ClusterName
+		})
+	default:
+		return nil, fmt.Errorf("invalid sort %q, must be created or name", filter.SortBy)
+	}
+
+	if filter.Reverse {
+		for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+			filtered[i], filtered[j] = filtered[j], filtered[i]
+		}
+	}
+
+	return filtered, nil
+}
+
+func matchesAny(value string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, candidate := range accepted {
+		if strings.EqualFold(value, candidate) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// creationTime reads the creation timestamp of a cluster, which the api stores either as
+// milliseconds since the epoch or as a formatted time
+func creationTime(cluster types.Cluster) time.Time {
+	if milliseconds, err := strconv.ParseInt(cluster.CreationTimestamp, 10, 64); err == nil {
+		return time.UnixMilli(milliseconds)
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999 -0700 MST"} {
+		if parsed, err := time.Parse(layout, cluster.CreationTimestamp); err == nil {
+			return parsed
+		}
+	}
+
+	return time.Time{}
+}
+
 // displayFormattedClusterInfo uses tabwriter to pretty print information on clusters using
 // the specified formatting
 func displayFormattedClusterInfo(clusters []types.Cluster) error {
+	clusterList := make([]clusterListItem, 0, len(clusters))
+	for _, cluster := range clusters {
+		clusterList = append(clusterList, clusterListItem{
+			Name:          cluster.ClusterName,
+			CreatedAt:     cluster.CreationTimestamp,
+			Status:        cluster.Status,
+			Type:          cluster.ClusterType,
+			CloudProvider: cluster.CloudProvider,
+			CloudRegion:   cluster.CloudRegion,
+			Domain:        clusterDomain(cluster),
+			GitProvider:   cluster.GitProvider,
+			GitOwner:      cluster.GitAuth.Owner,
+			LastCondition: cluster.LastCondition,
+		})
+	}
+
+	if progress.IsStructuredOutput() {
+		return progress.Document(clusterList)
+	}
+
 	header := `
-| NAME | CREATED AT | STATUS | TYPE | PROVIDER |
-| --- | --- | --- | --- | --- |
+| NAME | CREATED AT | STATUS | TYPE | PROVIDER | REGION | DOMAIN | GIT | LAST CONDITION |
+| --- | --- | --- | --- | --- | --- | --- | --- | --- |
 	`
 	content := ""
-	for _, cluster := range clusters {
-		content = content + fmt.Sprintf("|%s|%s|%s|%s|%s\n",
-			cluster.ClusterName,
-			cluster.CreationTimestamp,
-			cluster.Status,
-			cluster.ClusterType,
-			cluster.CloudProvider,
+	for _, item := range clusterList {
+		lastCondition := item.LastCondition
+		if len(lastCondition) > lastConditionWidth {
+			lastCondition = lastCondition[:lastConditionWidth-3] + "..."
+		}
+		git := item.GitProvider
+		if item.GitOwner != "" {
+			git = fmt.Sprintf("%s/%s", item.GitProvider, item.GitOwner)
+		}
+		content = content + fmt.Sprintf("|%s|%s|%s|%s|%s|%s|%s|%s|%s\n",
+			item.Name,
+			item.CreatedAt,
+			item.Status,
+			item.Type,
+			item.CloudProvider,
+			item.CloudRegion,
+			item.Domain,
+			git,
+			strings.ReplaceAll(lastCondition, "|", "/"),
 		)
 	}
 
@@ -35,3 +161,33 @@ func displayFormattedClusterInfo(clusters []types.Cluster) error {
 
 	return nil
 }
+
+// clusterDomain returns the fully qualified domain of a cluster
+func clusterDomain(cluster types.Cluster) string {
+	if cluster.SubdomainName != "" {
+		return fmt.Sprintf("%s.%s", cluster.SubdomainName, cluster.DomainName)
+	}
+
+	return cluster.DomainName
+}
+
+// waitForAPIDeployment waits for the kubefirst-api Deployment pods to be ready
+func waitForAPIDeployment(kcfg *k8s.KubernetesClient) error {
+	log.Info().Msg("Waiting for Kubefirst API Deployment...")
+	apiDeployment, err := k8s.ReturnDeploymentObject(
+		kcfg.Clientset,
+		"app.kubernetes.io/name",
+		"kubefirst-api",
+		namespace,
+		240,
+	)
+	if err != nil {
+		return fmt.Errorf("error looking for kubefirst api: %s", err)
+	}
+	_, err = k8s.WaitForDeploymentReady(kcfg.Clientset, apiDeployment, 300)
+	if err != nil {
+		return fmt.Errorf("error waiting for kubefirst api: %s", err)
+	}
+
+	return nil
+}
@@ -7,20 +7,33 @@ See the LICENSE file for more details.
 package progress
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/rs/zerolog/log"
 )
 
 // Commands
 func GetClusterInterval(clusterName string) tea.Cmd {
 	return tea.Every(time.Second*10, func(t time.Time) tea.Msg {
-		provisioningCluster, err := cluster.GetCluster(clusterName)
+		consoleClient, err := cluster.NewDefaultConsoleClient()
+		if err != nil {
+			return errorMsg{message: createErrorLog(err.Error()).message}
+		}
 
+		provisioningCluster, err := consoleClient.GetCluster(context.Background(), clusterName)
+		// a cluster removed while it is watched will never come back
+		if cluster.IsNotFound(err) {
+			return errorMsg{message: createErrorLog(fmt.Sprintf("cluster %s no longer exists", clusterName)).message}
+		}
 		if err != nil {
+			log.Warn().Msgf("unable to poll cluster %s, retrying: %s", clusterName, err)
 
+			return clusterPollFailedMsg{err: err}
 		}
 
 		return CusterProvisioningMsg(provisioningCluster)
@@ -97,3 +110,29 @@ func BuildCompletedSteps(cluster types.Cluster, model progressModel) ([]string,
 
 	return completedSteps, nextStep
 }
+
+// ProvisioningCheck is a provisioning step of a cluster and whether it has passed
+type ProvisioningCheck struct {
+	Label  string `json:"label" yaml:"label"`
+	Passed bool   `json:"passed" yaml:"passed"`
+}
+
+// ProvisioningChecks returns every provisioning step of a cluster in the order they run
+func ProvisioningChecks(cluster types.Cluster) []ProvisioningCheck {
+	return []ProvisioningCheck{
+		{Label: CompletedStepsLabels.install_tools_check, Passed: cluster.InstallToolsCheck},
+		{Label: CompletedStepsLabels.domain_liveness_check, Passed: cluster.DomainLivenessCheck},
+		{Label: CompletedStepsLabels.kbot_setup_check, Passed: cluster.KbotSetupCheck},
+		{Label: CompletedStepsLabels.git_init_check, Passed: cluster.GitInitCheck},
+		{Label: CompletedStepsLabels.gitops_ready_check, Passed: cluster.GitopsReadyCheck},
+		{Label: CompletedStepsLabels.git_terraform_apply_check, Passed: cluster.GitTerraformApplyCheck},
+		{Label: CompletedStepsLabels.gitops_pushed_check, Passed: cluster.GitopsPushedCheck},
+		{Label: CompletedStepsLabels.cloud_terraform_apply_check, Passed: cluster.CloudTerraformApplyCheck},
+		{Label: CompletedStepsLabels.cluster_secrets_created_check, Passed: cluster.ClusterSecretsCreatedCheck},
+		{Label: CompletedStepsLabels.argocd_install_check, Passed: cluster.ArgoCDInstallCheck},
+		{Label: CompletedStepsLabels.argocd_initialize_check, Passed: cluster.ArgoCDInitializeCheck},
+		{Label: CompletedStepsLabels.vault_initialized_check, Passed: cluster.VaultInitializedCheck},
+		{Label: CompletedStepsLabels.vault_terraform_apply_check, Passed: cluster.VaultTerraformApplyCheck},
+		{Label: CompletedStepsLabels.users_terraform_apply_check, Passed: cluster.UsersTerraformApplyCheck},
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// EventsFormatNDJSON writes one json document per line
+	EventsFormatNDJSON = "ndjson"
+
+	EventStepStarted   = "step_started"
+	EventStepCompleted = "step_completed"
+	EventError         = "error"
+	EventProvisioned   = "provisioned"
+)
+
+// Event describes a single provisioning state transition
+type Event struct {
+	Type           string    `json:"type"`
+	Timestamp      time.Time `json:"timestamp"`
+	ClusterName    string    `json:"cluster_name"`
+	Step           string    `json:"step,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	ElapsedSeconds float64   `json:"elapsed_seconds"`
+}
+
+// eventStream records the transitions observed by the cluster poller
+type eventStream struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	start   time.Time
+
+	completedSteps map[string]bool
+	currentStep    string
+	finished       bool
+}
+
+var events *eventStream
+
+// OpenEventsFile starts writing provisioning events to path, elapsed time is measured from this call
+func OpenEventsFile(path string, format string) error {
+	if format != EventsFormatNDJSON {
+		return fmt.Errorf("invalid events format %q, the only supported format is %s", format, EventsFormatNDJSON)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open events file %s: %s", path, err)
+	}
+
+	events = &eventStream{
+		file:           file,
+		encoder:        json.NewEncoder(file),
+		start:          time.Now(),
+		completedSteps: map[string]bool{},
+	}
+
+	return nil
+}
+
+// recordClusterEvents emits an event for every transition between the last poll and this one
+func recordClusterEvents(cluster types.Cluster) {
+	if events == nil {
+		return
+	}
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	if events.finished {
+		return
+	}
+
+	completedSteps, nextStep := BuildCompletedSteps(cluster, progressModel{})
+	if len(completedSteps) == 0 {
+		nextStep = CompletedStepsLabels.install_tools_check
+	}
+	for _, step := range completedSteps {
+		if events.completedSteps[step] {
+			continue
+		}
+		events.completedSteps[step] = true
+		events.emit(EventStepCompleted, cluster.ClusterName, step, "")
+	}
+
+	switch cluster.Status {
+	case "error":
+		events.finish(EventError, cluster.ClusterName, nextStep, cluster.LastCondition)
+	case "provisioned":
+		events.finish(EventProvisioned, cluster.ClusterName, "", "")
+	default:
+		if nextStep != "" && nextStep != events.currentStep {
+			events.currentStep = nextStep
+			events.emit(EventStepStarted, cluster.ClusterName, nextStep, "")
+		}
+	}
+}
+
+// StepEvents records the steps of an install that runs locally instead of being polled from
+// the console API, such as k3d. It satisfies steps.Observer
+type StepEvents struct {
+	ClusterName string
+}
+
+func (e StepEvents) StepStarted(name string) {
+	recordStepEvent(EventStepStarted, e.ClusterName, name, "")
+}
+
+func (e StepEvents) StepCompleted(name string) {
+	recordStepEvent(EventStepCompleted, e.ClusterName, name, "")
+}
+
+func (e StepEvents) StepFailed(name string, err error) {
+	recordStepEvent(EventError, e.ClusterName, name, err.Error())
+}
+
+// Provisioned records that every step of the install completed
+func (e StepEvents) Provisioned() {
+	recordStepEvent(EventProvisioned, e.ClusterName, "", "")
+}
+
+func recordStepEvent(eventType string, clusterName string, step string, message string) {
+	if events == nil {
+		return
+	}
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	if events.finished {
+		return
+	}
+
+	switch eventType {
+	case EventError, EventProvisioned:
+		events.finish(eventType, clusterName, step, message)
+	default:
+		events.emit(eventType, clusterName, step, message)
+	}
+}
+
+// CloseEventsFile flushes and closes the events file, it is called once the progress
+// program quits and before the process exits
+func CloseEventsFile() {
+	if events == nil {
+		return
+	}
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+
+	if events.file == nil {
+		return
+	}
+
+	err := events.file.Sync()
+	if err != nil {
+		log.Warn().Msgf("unable to sync events file %s: %s", events.file.Name(), err)
+	}
+	err = events.file.Close()
+	if err != nil {
+		log.Warn().Msgf("unable to close events file %s: %s", events.file.Name(), err)
+	}
+	events.file = nil
+	events.finished = true
+}
+
+// finish emits the last event of the stream and syncs it to disk so that it survives
+// the process being killed right after
+func (s *eventStream) finish(eventType string, clusterName string, step string, message string) {
+	s.finished = true
+	s.emit(eventType, clusterName, step, message)
+
+	err := s.file.Sync()
+	if err != nil {
+		log.Warn().Msgf("unable to sync events file %s: %s", s.file.Name(), err)
+	}
+}
+
+func (s *eventStream) emit(eventType string, clusterName string, step string, message string) {
+	now := time.Now()
+
+	err := s.encoder.Encode(Event{
+		Type:           eventType,
+		Timestamp:      now.UTC(),
+		ClusterName:    clusterName,
+		Step:           step,
+		Message:        message,
+		ElapsedSeconds: now.Sub(s.start).Round(time.Second).Seconds(),
+	})
+	if err != nil {
+		log.Warn().Msgf("unable to write %s event to %s: %s", eventType, s.file.Name(), err)
+	}
+}
@@ -20,8 +20,13 @@ import (
 )
 
 func renderMessage(message string) string {
+	style := glamour.WithStyles(StyleConfig)
+	if isPlain {
+		style = glamour.WithStandardStyle("notty")
+	}
+
 	r, _ := glamour.NewTermRenderer(
-		glamour.WithStyles(StyleConfig),
+		style,
 		glamour.WithEmoji(),
 	)
 
@@ -114,7 +119,7 @@ func DisplaySuccessMessage(cluster types.Cluster) successMsg {
 ## GitLab
 ### Git Owner   ` + fmt.Sprintf("`%s`", cluster.GitAuth.Owner) + `
 ### Repos       ` + fmt.Sprintf("`https://%s.com/%s/gitops` \n\n", cluster.GitProvider, cluster.GitAuth.Owner) +
-		fmt.Sprintf("`            https://%s.com/%s/gitops`", cluster.GitProvider, cluster.GitAuth.Owner) + `
+		fmt.Sprintf("`            https://%s.com/%s/metaphor`", cluster.GitProvider, cluster.GitAuth.Owner) + `
 ## Kubefirst Console
 ### URL         ` + fmt.Sprintf("`https://kubefirst.%s`", cluster.DomainName) + `
 ## Argo CD
@@ -139,6 +144,20 @@ func DisplaySuccessMessage(cluster types.Cluster) successMsg {
 }
 
 func DisplayCredentials(cluster types.Cluster) {
+	if IsStructuredOutput() {
+		err := Document(rootCredentials{
+			ClusterName:    cluster.ClusterName,
+			ArgoCDPassword: cluster.ArgoCDPassword,
+			KbotPassword:   cluster.VaultAuth.KbotPassword,
+			VaultRootToken: cluster.VaultAuth.RootToken,
+		})
+		if err != nil {
+			Error(err.Error())
+		}
+
+		return
+	}
+
 	header := `
 ##
 # Root Credentials
@@ -189,6 +208,25 @@ func Error(message string) {
 	Progress.Send(renderedMessage)
 }
 
+// WatchProvisioning attaches the progress view to a cluster that is already known to the console API
+func WatchProvisioning(cluster types.Cluster) {
+	logFile := viper.GetString("k1-paths.log-file")
+
+	header := `
+##
+# Watching cluster ` + fmt.Sprintf("`%s`", cluster.ClusterName) + `
+
+### :bulb: To view verbose logs run below command in new terminal:
+` + fmt.Sprintf("##### **tail -f -n +1 %s**", logFile) + `
+`
+
+	Progress.Send(headerMsg{
+		message: renderMessage(header),
+	})
+
+	Progress.Send(CusterProvisioningMsg(cluster))
+}
+
 func StartProvisioning(clusterName string, estimatedTime int) {
 	provisioningMessage := startProvision{
 		clusterName: clusterName,
@@ -0,0 +1,79 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// OutputText renders glamour markdown, the default
+	OutputText = "text"
+	// OutputJSON writes a single json document to stdout
+	OutputJSON = "json"
+	// OutputYAML writes a single yaml document to stdout
+	OutputYAML = "yaml"
+)
+
+var outputFormat = OutputText
+
+// SetOutputFormat selects how commands report their results
+func SetOutputFormat(format string) error {
+	switch format {
+	case OutputText, OutputJSON, OutputYAML:
+		outputFormat = format
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, must be one of %s, %s or %s", format, OutputText, OutputJSON, OutputYAML)
+	}
+}
+
+// GetOutputFormat returns the selected output format
+func GetOutputFormat() string {
+	return outputFormat
+}
+
+// IsStructuredOutput reports whether commands should emit json or yaml documents instead of markdown
+func IsStructuredOutput() bool {
+	return outputFormat == OutputJSON || outputFormat == OutputYAML
+}
+
+// MessageWriter returns where human readable messages should be written so that
+// stdout stays parseable when a structured output format is selected
+func MessageWriter() io.Writer {
+	if IsStructuredOutput() {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+// Document writes v to stdout in the selected structured format and stops the progress program
+func Document(v interface{}) error {
+	var out []byte
+	var err error
+	switch outputFormat {
+	case OutputYAML:
+		out, err = yaml.Marshal(v)
+	default:
+		out, err = json.MarshalIndent(v, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("unable to render %s output: %s", outputFormat, err)
+	}
+
+	_, err = os.Stdout.Write(out)
+	Progress.Quit()
+
+	return err
+}
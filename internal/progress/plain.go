@@ -0,0 +1,151 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// plainProgram drives the same progressModel as the bubbletea program, but appends
+// each change to a writer instead of redrawing the terminal. It is used in ci, when
+// stdout is not a terminal, and when a structured output format is selected
+type plainProgram struct {
+	model progressModel
+	out   io.Writer
+
+	msgs     chan tea.Msg
+	done     chan struct{}
+	quitOnce sync.Once
+
+	// what has already been written, so every line is printed once
+	header         string
+	printedSteps   map[string]bool
+	nextStep       string
+	error          string
+	successMessage string
+}
+
+func newPlainProgram(model progressModel, out io.Writer) *plainProgram {
+	return &plainProgram{
+		model:        model,
+		out:          out,
+		msgs:         make(chan tea.Msg),
+		done:         make(chan struct{}),
+		printedSteps: map[string]bool{},
+	}
+}
+
+// Send delivers a message to the model, it is a no-op once the program has quit
+func (p *plainProgram) Send(msg tea.Msg) {
+	select {
+	case p.msgs <- msg:
+	case <-p.done:
+	}
+}
+
+// Quit stops Run
+func (p *plainProgram) Quit() {
+	p.quitOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// Run processes messages until Quit is called or the model returns tea.Quit
+func (p *plainProgram) Run() (tea.Model, error) {
+	for {
+		select {
+		case <-p.done:
+			return p.model, nil
+		case msg := <-p.msgs:
+			model, cmd := p.model.Update(msg)
+			p.model = model.(progressModel)
+			p.print()
+			p.exec(cmd)
+		}
+	}
+}
+
+// exec runs a command in the background and feeds its result back into the program
+func (p *plainProgram) exec(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	go func() {
+		msg := cmd()
+		if _, ok := msg.(tea.QuitMsg); ok {
+			p.Quit()
+			return
+		}
+		if msg != nil {
+			p.Send(msg)
+		}
+	}()
+}
+
+// print writes whatever changed in the model since the last message
+func (p *plainProgram) print() {
+	m := p.model
+
+	if m.header != "" && m.header != p.header {
+		p.header = m.header
+		p.write(m.header)
+	}
+
+	for _, step := range m.completedSteps {
+		if p.printedSteps[step] {
+			continue
+		}
+		p.printedSteps[step] = true
+		p.write(renderMessage(fmt.Sprintf(":white_check_mark: %s", step)))
+	}
+
+	for _, step := range m.provisionSteps {
+		switch {
+		case step.isCompleted() && !p.printedSteps[step.key]:
+			p.printedSteps[step.key] = true
+			p.write(renderMessage(step.render(time.Now())))
+		case !step.isCompleted() && !step.firstSeen.IsZero() && !p.printedSteps[step.key+"-started"]:
+			p.printedSteps[step.key+"-started"] = true
+			message := fmt.Sprintf(":dizzy: %s", step.label)
+			if m.eta != "" {
+				message = fmt.Sprintf("%s `%s`", message, m.eta)
+			}
+			p.write(renderMessage(message))
+		}
+	}
+
+	if m.nextStep != "" && m.nextStep != p.nextStep {
+		p.write(m.nextStep)
+	}
+	p.nextStep = m.nextStep
+
+	if m.error != "" && m.error != p.error {
+		p.error = m.error
+		p.write(m.error)
+	}
+
+	if m.successMessage != "" && m.successMessage != p.successMessage {
+		p.successMessage = m.successMessage
+		p.write(m.successMessage)
+	}
+}
+
+func (p *plainProgram) write(message string) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return
+	}
+
+	fmt.Fprintln(p.out, message)
+}
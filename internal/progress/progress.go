@@ -8,13 +8,41 @@ package progress
 
 import (
 	"fmt"
+	"io"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/kubefirst/kubefirst-api/pkg/types"
+	"github.com/kubefirst/kubefirst/internal/cluster"
 	"github.com/spf13/viper"
+	"golang.org/x/exp/slices"
 )
 
-var Progress *tea.Program
+// Program is satisfied by the bubbletea program and by the plain backend
+type Program interface {
+	Send(msg tea.Msg)
+	Quit()
+	Run() (tea.Model, error)
+}
+
+var Progress Program
+
+// exitCode is non-zero once an error was reported or a provisioning cluster failed
+var exitCode int
+
+// ExitCode returns the status the process should exit with once Progress.Run returns
+func ExitCode() int {
+	return exitCode
+}
+
+// SetExitCode sets the status the process exits with for commands that report a result
+// rather than an error, such as a failed health check
+func SetExitCode(code int) {
+	exitCode = code
+}
+
+// isPlain is true when the plain backend is in use, messages are then rendered without colors
+var isPlain bool
 
 func NewModel() progressModel {
 	return progressModel{
@@ -27,6 +55,12 @@ func InitializeProgressTerminal() {
 	Progress = tea.NewProgram(NewModel())
 }
 
+// InitializePlainProgress replaces the interactive terminal with line based output written to out
+func InitializePlainProgress(out io.Writer) {
+	isPlain = true
+	Progress = newPlainProgram(NewModel(), out)
+}
+
 func (m progressModel) Init() tea.Cmd {
 	return nil
 }
@@ -50,12 +84,15 @@ func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case completeStep:
-		m.completedSteps = append(m.completedSteps, msg.message)
+		if !slices.Contains(m.completedSteps, msg.message) {
+			m.completedSteps = append(m.completedSteps, msg.message)
+		}
 		m.nextStep = ""
 		return m, nil
 
 	case errorMsg:
 		m.error = msg.message
+		exitCode = 1
 		return m, tea.Quit
 
 	case successMsg:
@@ -68,13 +105,25 @@ func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case CusterProvisioningMsg:
 		m.provisioningCluster = types.Cluster(msg)
-		completedSteps, nextStep := BuildCompletedSteps(types.Cluster(msg), m)
-		m.completedSteps = append(m.completedSteps, completedSteps...)
-		m.nextStep = renderMessage(fmt.Sprintf(":dizzy: %s", nextStep))
+		m.clusterName = m.provisioningCluster.ClusterName
+		recordClusterEvents(m.provisioningCluster)
+		now := time.Now()
+		completedSteps, _ := BuildCompletedSteps(types.Cluster(msg), m)
+		if m.provisionSteps == nil {
+			m.provisionSteps = newProvisionSteps()
+		}
+		updateProvisionSteps(m.provisionSteps, completedSteps, now)
+		m.nextStep = ""
+
+		m.eta = ""
+		if remaining, ok := estimateRemaining(m.provisioningCluster.CloudProvider, m.provisionSteps, now); ok {
+			m.eta = fmt.Sprintf("about %s remaining", formatDuration(remaining))
+		}
 
-		if m.provisioningCluster.Status == "error" {
-			errorMessage := createErrorLog(m.provisioningCluster.LastCondition)
+		if err := cluster.WatchError(m.provisioningCluster); err != nil {
+			errorMessage := createErrorLog(err.Error())
 			m.error = errorMessage.message
+			exitCode = 1
 
 			return m, tea.Quit
 		}
@@ -82,14 +131,21 @@ func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.provisioningCluster.Status == "provisioned" {
 			m.isProvisioned = true
 			m.nextStep = ""
-			viper.Set("kubefirst-checks.cluster-install-complete", true)
-			viper.WriteConfig()
+			recordStepHistory(m.provisioningCluster.CloudProvider, m.provisionSteps)
+			// a watched cluster may not be the one this machine installed
+			if m.clusterName == viper.GetString("flags.cluster-name") {
+				viper.Set("kubefirst-checks.cluster-install-complete", true)
+				viper.WriteConfig()
+			}
 
 			return m, AddSuccesMessage(m.provisioningCluster)
 		}
 
 		return m, GetClusterInterval(m.clusterName)
 
+	case clusterPollFailedMsg:
+		return m, GetClusterInterval(m.clusterName)
+
 	default:
 		return m, nil
 	}
@@ -108,9 +164,21 @@ func (m progressModel) View() string {
 			completedSteps = completedSteps + renderMessage(fmt.Sprintf(":white_check_mark: %s", m.completedSteps[i]))
 		}
 
+		timeline := ""
+		now := time.Now()
+		for _, step := range m.provisionSteps {
+			if line := step.render(now); line != "" {
+				timeline = timeline + renderMessage(line)
+			}
+		}
+		if m.eta != "" && m.error == "" {
+			timeline = timeline + renderMessage(fmt.Sprintf(":alarm_clock: %s", m.eta))
+		}
+
 		if m.header != "" {
 			return m.header + "\n\n" +
 				completedSteps +
+				timeline +
 				m.nextStep + "\n\n" +
 				m.error + "\n\n"
 		}
@@ -0,0 +1,274 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// stepHistoryFile stores the duration of previous provisioning steps per cloud provider
+	stepHistoryFile = "provision-history.json"
+	// stepHistorySize is the number of durations kept per step
+	stepHistorySize = 5
+)
+
+// provisionStep tracks a single kubefirst api check during provisioning
+type provisionStep struct {
+	key       string
+	label     string
+	firstSeen time.Time
+	completed time.Time
+	// partial is set when the step was already running when the progress view attached
+	partial bool
+}
+
+// newProvisionSteps returns the 14 provisioning steps in the order the api runs them
+func newProvisionSteps() []provisionStep {
+	return []provisionStep{
+		{key: "install_tools_check", label: CompletedStepsLabels.install_tools_check},
+		{key: "domain_liveness_check", label: CompletedStepsLabels.domain_liveness_check},
+		{key: "kbot_setup_check", label: CompletedStepsLabels.kbot_setup_check},
+		{key: "git_init_check", label: CompletedStepsLabels.git_init_check},
+		{key: "gitops_ready_check", label: CompletedStepsLabels.gitops_ready_check},
+		{key: "git_terraform_apply_check", label: CompletedStepsLabels.git_terraform_apply_check},
+		{key: "gitops_pushed_check", label: CompletedStepsLabels.gitops_pushed_check},
+		{key: "cloud_terraform_apply_check", label: CompletedStepsLabels.cloud_terraform_apply_check},
+		{key: "cluster_secrets_created_check", label: CompletedStepsLabels.cluster_secrets_created_check},
+		{key: "argocd_install_check", label: CompletedStepsLabels.argocd_install_check},
+		{key: "argocd_initialize_check", label: CompletedStepsLabels.argocd_initialize_check},
+		{key: "vault_initialized_check", label: CompletedStepsLabels.vault_initialized_check},
+		{key: "vault_terraform_apply_check", label: CompletedStepsLabels.vault_terraform_apply_check},
+		{key: "users_terraform_apply_check", label: CompletedStepsLabels.users_terraform_apply_check},
+	}
+}
+
+// isCompleted reports whether the step finished while or before it was observed
+func (s provisionStep) isCompleted() bool {
+	return !s.completed.IsZero()
+}
+
+// duration returns how long the step ran, it is only known when the step was seen starting
+func (s provisionStep) duration() (time.Duration, bool) {
+	if s.firstSeen.IsZero() || s.partial || s.completed.Before(s.firstSeen) {
+		return 0, false
+	}
+
+	return s.completed.Sub(s.firstSeen), true
+}
+
+// render returns the timeline line for the step
+func (s provisionStep) render(now time.Time) string {
+	switch {
+	case s.isCompleted():
+		if duration, ok := s.duration(); ok {
+			return fmt.Sprintf(":white_check_mark: %s `%s`", s.label, formatDuration(duration))
+		}
+		return fmt.Sprintf(":white_check_mark: %s", s.label)
+	case s.partial:
+		return fmt.Sprintf(":dizzy: %s", s.label)
+	case !s.firstSeen.IsZero():
+		return fmt.Sprintf(":dizzy: %s `running for %s`", s.label, formatDuration(now.Sub(s.firstSeen)))
+	default:
+		return ""
+	}
+}
+
+// updateProvisionSteps marks newly completed steps and the step that is now running
+func updateProvisionSteps(steps []provisionStep, completedLabels []string, now time.Time) {
+	known := map[string]bool{}
+	for _, step := range steps {
+		known[step.label] = true
+	}
+	// labels of steps the timeline does not track are ignored
+	completed := map[string]bool{}
+	for _, label := range completedLabels {
+		if known[label] {
+			completed[label] = true
+		}
+	}
+
+	// when the first poll already reports completed steps the view attached to an
+	// in-flight provisioning, the start of those steps is unknown
+	attached := len(completed) > 0
+	for _, step := range steps {
+		if !step.firstSeen.IsZero() || step.isCompleted() {
+			attached = false
+			break
+		}
+	}
+
+	for i := range steps {
+		if completed[steps[i].label] {
+			if steps[i].completed.IsZero() {
+				steps[i].completed = now
+				// a step that started and finished between two polls started when the previous one finished
+				if steps[i].firstSeen.IsZero() && !attached {
+					steps[i].firstSeen = now
+					if i > 0 && steps[i-1].isCompleted() {
+						steps[i].firstSeen = steps[i-1].completed
+					}
+				}
+			}
+			continue
+		}
+
+		// the first step that is not complete is the one running
+		if steps[i].firstSeen.IsZero() {
+			steps[i].firstSeen = now
+			steps[i].partial = attached
+		}
+		return
+	}
+}
+
+// estimateRemaining returns the time left based on the recorded history of the cloud provider
+func estimateRemaining(cloudProvider string, steps []provisionStep, now time.Time) (time.Duration, bool) {
+	averages := loadStepHistory().averages(cloudProvider)
+	if len(averages) == 0 {
+		return 0, false
+	}
+
+	var remaining time.Duration
+	for _, step := range steps {
+		if step.isCompleted() {
+			continue
+		}
+
+		average, ok := averages[step.key]
+		if !ok {
+			return 0, false
+		}
+
+		if !step.firstSeen.IsZero() {
+			average = average - now.Sub(step.firstSeen)
+			if average < 0 {
+				average = 0
+			}
+		}
+		remaining += average
+	}
+
+	return remaining, true
+}
+
+// EstimatedMinutes returns the expected provisioning time for a cloud provider based on
+// previous runs recorded on this machine, or fallback when there is no complete history
+func EstimatedMinutes(cloudProvider string, fallback int) int {
+	remaining, ok := estimateRemaining(cloudProvider, newProvisionSteps(), time.Now())
+	if !ok {
+		return fallback
+	}
+
+	return int(math.Ceil(remaining.Minutes()))
+}
+
+// stepHistory maps cloud provider to step key to the most recent durations in seconds
+type stepHistory map[string]map[string][]float64
+
+func stepHistoryPath() (string, error) {
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homePath, ".k1", stepHistoryFile), nil
+}
+
+func loadStepHistory() stepHistory {
+	history := stepHistory{}
+
+	path, err := stepHistoryPath()
+	if err != nil {
+		return history
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return history
+	}
+
+	err = json.Unmarshal(content, &history)
+	if err != nil {
+		log.Warn().Msgf("ignoring unreadable provisioning history %s: %s", path, err)
+		return stepHistory{}
+	}
+
+	return history
+}
+
+// averages returns the mean duration of every step recorded for a cloud provider
+func (h stepHistory) averages(cloudProvider string) map[string]time.Duration {
+	averages := map[string]time.Duration{}
+	for key, durations := range h[cloudProvider] {
+		if len(durations) == 0 {
+			continue
+		}
+
+		total := 0.0
+		for _, duration := range durations {
+			total += duration
+		}
+		averages[key] = time.Duration(total / float64(len(durations)) * float64(time.Second))
+	}
+
+	return averages
+}
+
+// recordStepHistory appends the duration of every step that was seen running to the history file
+func recordStepHistory(cloudProvider string, steps []provisionStep) {
+	if cloudProvider == "" {
+		return
+	}
+
+	history := loadStepHistory()
+	if history[cloudProvider] == nil {
+		history[cloudProvider] = map[string][]float64{}
+	}
+
+	for _, step := range steps {
+		duration, ok := step.duration()
+		if !ok {
+			continue
+		}
+
+		durations := append(history[cloudProvider][step.key], duration.Seconds())
+		if len(durations) > stepHistorySize {
+			durations = durations[len(durations)-stepHistorySize:]
+		}
+		history[cloudProvider][step.key] = durations
+	}
+
+	path, err := stepHistoryPath()
+	if err != nil {
+		log.Warn().Msgf("unable to record provisioning history: %s", err)
+		return
+	}
+
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		log.Warn().Msgf("unable to record provisioning history: %s", err)
+		return
+	}
+
+	err = os.WriteFile(path, content, 0644)
+	if err != nil {
+		log.Warn().Msgf("unable to record provisioning history to %s: %s", path, err)
+	}
+}
+
+// formatDuration renders a duration rounded to the second, e.g. 4m12s
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,343 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// labels returns the labels of the first n provisioning steps
+func labels(n int) []string {
+	steps := newProvisionSteps()
+	labels := []string{}
+	for _, step := range steps[:n] {
+		labels = append(labels, step.label)
+	}
+
+	return labels
+}
+
+func TestUpdateProvisionSteps(t *testing.T) {
+	start := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	type poll struct {
+		after     time.Duration
+		completed []string
+	}
+	type wantStep struct {
+		index     int
+		completed bool
+		running   bool
+		partial   bool
+		duration  time.Duration
+		// noDuration is set when the duration of a completed step is unknown
+		noDuration bool
+	}
+
+	tests := []struct {
+		name  string
+		polls []poll
+		want  []wantStep
+	}{
+		{
+			name:  "first poll starts the first step",
+			polls: []poll{{after: 0}},
+			want: []wantStep{
+				{index: 0, running: true},
+				{index: 1},
+			},
+		},
+		{
+			name: "step completes between polls",
+			polls: []poll{
+				{after: 0},
+				{after: 90 * time.Second, completed: labels(1)},
+			},
+			want: []wantStep{
+				{index: 0, completed: true, duration: 90 * time.Second},
+				{index: 1, running: true},
+			},
+		},
+		{
+			name: "several steps complete between two polls",
+			polls: []poll{
+				{after: 0},
+				{after: time.Minute, completed: labels(1)},
+				{after: 3 * time.Minute, completed: labels(3)},
+			},
+			want: []wantStep{
+				{index: 0, completed: true, duration: time.Minute},
+				{index: 1, completed: true, duration: 2 * time.Minute},
+				// it started when the previous step finished, on the same poll
+				{index: 2, completed: true, duration: 0},
+				{index: 3, running: true},
+			},
+		},
+		{
+			name:  "attached to a provisioning in flight",
+			polls: []poll{{after: 0, completed: labels(3)}},
+			want: []wantStep{
+				{index: 0, completed: true, noDuration: true},
+				{index: 2, completed: true, noDuration: true},
+				{index: 3, running: true, partial: true},
+			},
+		},
+		{
+			name: "partial step keeps no duration",
+			polls: []poll{
+				{after: 0, completed: labels(2)},
+				{after: 5 * time.Minute, completed: labels(3)},
+			},
+			want: []wantStep{
+				{index: 2, completed: true, partial: true, noDuration: true},
+				{index: 3, running: true},
+			},
+		},
+		{
+			name:  "unknown steps are ignored",
+			polls: []poll{{after: 0, completed: []string{"a step the api added later"}}},
+			want: []wantStep{
+				{index: 0, running: true},
+			},
+		},
+		{
+			name: "step reported out of order waits for the earlier one",
+			polls: []poll{
+				{after: 0},
+				{after: time.Minute, completed: []string{labels(3)[2]}},
+			},
+			want: []wantStep{
+				{index: 0, running: true},
+				{index: 2},
+			},
+		},
+		{
+			name: "completion is recorded once",
+			polls: []poll{
+				{after: 0},
+				{after: time.Minute, completed: labels(1)},
+				{after: 2 * time.Minute, completed: labels(1)},
+			},
+			want: []wantStep{
+				{index: 0, completed: true, duration: time.Minute},
+				{index: 1, running: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := newProvisionSteps()
+			for _, p := range tt.polls {
+				updateProvisionSteps(steps, p.completed, start.Add(p.after))
+			}
+
+			for _, want := range tt.want {
+				step := steps[want.index]
+				if step.isCompleted() != want.completed {
+					t.Errorf("step %d completed = %t, want %t", want.index, step.isCompleted(), want.completed)
+				}
+				running := !step.isCompleted() && !step.firstSeen.IsZero()
+				if running != want.running {
+					t.Errorf("step %d running = %t, want %t", want.index, running, want.running)
+				}
+				if step.partial != want.partial {
+					t.Errorf("step %d partial = %t, want %t", want.index, step.partial, want.partial)
+				}
+				if !want.completed {
+					continue
+				}
+
+				duration, ok := step.duration()
+				if ok == want.noDuration {
+					t.Errorf("step %d has a duration = %t, want %t", want.index, ok, !want.noDuration)
+				}
+				if ok && duration != want.duration {
+					t.Errorf("step %d duration = %s, want %s", want.index, duration, want.duration)
+				}
+			}
+		})
+	}
+}
+
+// writeStepHistory stores a provisioning history in a temporary home directory
+func writeStepHistory(t *testing.T, history stepHistory) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if history == nil {
+		return
+	}
+
+	err := os.MkdirAll(filepath.Join(home, ".k1"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(home, ".k1", stepHistoryFile), content, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// minuteHistory records every step as having taken the given minutes
+func minuteHistory(minutes ...float64) map[string][]float64 {
+	durations := []float64{}
+	for _, m := range minutes {
+		durations = append(durations, m*60)
+	}
+
+	history := map[string][]float64{}
+	for _, step := range newProvisionSteps() {
+		history[step.key] = durations
+	}
+
+	return history
+}
+
+func TestEstimateRemaining(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	stepCount := len(newProvisionSteps())
+
+	incomplete := minuteHistory(1)
+	delete(incomplete, "users_terraform_apply_check")
+
+	tests := []struct {
+		name    string
+		history stepHistory
+		// completed steps and how long the running step has been going
+		completed int
+		running   time.Duration
+		want      time.Duration
+		wantOK    bool
+	}{
+		{
+			name:   "no history",
+			wantOK: false,
+		},
+		{
+			name:    "history of another cloud provider",
+			history: stepHistory{"aws": minuteHistory(1)},
+			wantOK:  false,
+		},
+		{
+			name:    "nothing started",
+			history: stepHistory{"civo": minuteHistory(1)},
+			want:    time.Duration(stepCount) * time.Minute,
+			wantOK:  true,
+		},
+		{
+			name:    "durations are averaged",
+			history: stepHistory{"civo": minuteHistory(1, 3)},
+			want:    time.Duration(stepCount) * 2 * time.Minute,
+			wantOK:  true,
+		},
+		{
+			name:      "completed steps and the running one",
+			history:   stepHistory{"civo": minuteHistory(2)},
+			completed: 4,
+			running:   30 * time.Second,
+			want:      time.Duration(stepCount-4)*2*time.Minute - 30*time.Second,
+			wantOK:    true,
+		},
+		{
+			name:      "running step over its average counts as done",
+			history:   stepHistory{"civo": minuteHistory(1)},
+			completed: 4,
+			running:   5 * time.Minute,
+			want:      time.Duration(stepCount-5) * time.Minute,
+			wantOK:    true,
+		},
+		{
+			name:      "every step completed",
+			history:   stepHistory{"civo": minuteHistory(1)},
+			completed: stepCount,
+			want:      0,
+			wantOK:    true,
+		},
+		{
+			name:    "a step without history",
+			history: stepHistory{"civo": incomplete},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeStepHistory(t, tt.history)
+
+			steps := newProvisionSteps()
+			for i := 0; i < tt.completed; i++ {
+				steps[i].firstSeen = now.Add(-time.Hour)
+				steps[i].completed = now.Add(-time.Minute)
+			}
+			if tt.completed < len(steps) && tt.running > 0 {
+				steps[tt.completed].firstSeen = now.Add(-tt.running)
+			}
+
+			remaining, ok := estimateRemaining("civo", steps, now)
+			if ok != tt.wantOK {
+				t.Fatalf("estimateRemaining() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if ok && remaining != tt.want {
+				t.Errorf("estimateRemaining() = %s, want %s", remaining, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimatedMinutes(t *testing.T) {
+	writeStepHistory(t, nil)
+	if got := EstimatedMinutes("civo", 35); got != 35 {
+		t.Errorf("EstimatedMinutes() without history = %d, want the fallback 35", got)
+	}
+
+	writeStepHistory(t, stepHistory{"civo": minuteHistory(0.5)})
+	want := len(newProvisionSteps())/2 + len(newProvisionSteps())%2
+	if got := EstimatedMinutes("civo", 35); got != want {
+		t.Errorf("EstimatedMinutes() = %d, want %d", got, want)
+	}
+}
+
+func TestRecordStepHistoryKeepsRecentDurations(t *testing.T) {
+	writeStepHistory(t, nil)
+	err := os.MkdirAll(filepath.Join(os.Getenv("HOME"), ".k1"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	for run := 1; run <= stepHistorySize+2; run++ {
+		steps := newProvisionSteps()
+		steps[0].firstSeen = start
+		steps[0].completed = start.Add(time.Duration(run) * time.Second)
+		// attached steps have no duration to record
+		steps[1].firstSeen = start
+		steps[1].completed = start.Add(time.Minute)
+		steps[1].partial = true
+
+		recordStepHistory("civo", steps)
+	}
+
+	history := loadStepHistory()["civo"]
+	durations := history["install_tools_check"]
+	if len(durations) != stepHistorySize {
+		t.Fatalf("recorded %d durations, want %d", len(durations), stepHistorySize)
+	}
+	if durations[0] != 3 || durations[len(durations)-1] != float64(stepHistorySize+2) {
+		t.Errorf("recorded durations %v, want the %d most recent", durations, stepHistorySize)
+	}
+	if _, exists := history["domain_liveness_check"]; exists {
+		t.Error("recorded a duration for a step that was already running when the view attached")
+	}
+}
@@ -23,6 +23,8 @@ type progressModel struct {
 	provisioningCluster types.Cluster
 	completedSteps      []string
 	nextStep            string
+	provisionSteps      []provisionStep
+	eta                 string
 	successMessage      string
 }
 
@@ -30,6 +32,10 @@ type progressModel struct {
 
 type CusterProvisioningMsg types.Cluster
 
+type clusterPollFailedMsg struct {
+	err error
+}
+
 type startProvision struct {
 	clusterName string
 }
@@ -56,6 +62,14 @@ type successMsg struct {
 
 // Custom
 
+// rootCredentials is the structured form of DisplayCredentials
+type rootCredentials struct {
+	ClusterName    string `json:"cluster_name" yaml:"cluster_name"`
+	ArgoCDPassword string `json:"argocd_password" yaml:"argocd_password"`
+	KbotPassword   string `json:"kbot_password" yaml:"kbot_password"`
+	VaultRootToken string `json:"vault_root_token" yaml:"vault_root_token"`
+}
+
 type ProvisionSteps struct {
 	install_tools_check           string
 	domain_liveness_check         string
@@ -7,6 +7,8 @@ See the LICENSE file for more details.
 package provision
 
 import (
+	"context"
+
 	runtimeTypes "github.com/kubefirst/kubefirst-api/pkg/types"
 	"github.com/kubefirst/kubefirst/internal/cluster"
 	"github.com/kubefirst/kubefirst/internal/progress"
@@ -21,21 +23,49 @@ func CreateMgmtCluster(gitAuth runtimeTypes.GitAuth, cliFlags types.CliFlags) {
 		cliFlags,
 	)
 
-	clusterCreated, err := cluster.GetCluster(clusterRecord.ClusterName)
+	if cliFlags.EventsFile != "" {
+		err := progress.OpenEventsFile(cliFlags.EventsFile, cliFlags.EventsFormat)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
+	}
+
+	ctx := context.Background()
+	consoleClient, err := cluster.NewDefaultConsoleClient()
 	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	clusterCreated, err := consoleClient.GetCluster(ctx, clusterRecord.ClusterName)
+	if err != nil && !cluster.IsNotFound(err) {
+		progress.Error(err.Error())
+		return
+	}
+	if cluster.IsNotFound(err) {
 		log.Info().Msg("cluster not found")
 	}
 
-	if !clusterCreated.InProgress {
-		err = cluster.CreateCluster(clusterRecord)
+	switch {
+	case clusterCreated.Status == "error":
+		err = consoleClient.ResetClusterProgress(ctx, clusterRecord.ClusterName)
 		if err != nil {
 			progress.Error(err.Error())
+			return
 		}
-	}
 
-	if clusterCreated.Status == "error" {
-		cluster.ResetClusterProgress(clusterRecord.ClusterName)
-		cluster.CreateCluster(clusterRecord)
+		err = consoleClient.CreateCluster(ctx, clusterRecord)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
+	case !clusterCreated.InProgress:
+		err = consoleClient.CreateCluster(ctx, clusterRecord)
+		if err != nil {
+			progress.Error(err.Error())
+			return
+		}
 	}
 
 	progress.StartProvisioning(clusterRecord.ClusterName, 35)
@@ -0,0 +1,42 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provision
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubefirst/kubefirst/internal/cluster"
+	"github.com/kubefirst/kubefirst/internal/progress"
+)
+
+// WatchCluster attaches the progress view to a cluster known to the console API
+func WatchCluster(clusterName string) {
+	consoleClient, err := cluster.NewDefaultConsoleClient()
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	clusterRecord, err := consoleClient.GetCluster(context.Background(), clusterName)
+	if cluster.IsNotFound(err) {
+		progress.Error(fmt.Sprintf("cluster %s not found", clusterName))
+		return
+	}
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	err = cluster.WatchError(clusterRecord)
+	if err != nil {
+		progress.Error(err.Error())
+		return
+	}
+
+	progress.WatchProvisioning(clusterRecord)
+}
@@ -0,0 +1,502 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kubefirst/metrics-client/pkg/telemetry"
+	"github.com/kubefirst/runtime/pkg/progressPrinter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+const (
+	// checksKey is the viper section holding the check of every step
+	checksKey = "kubefirst-checks"
+
+	StatusSkip    = "skip"
+	StatusRerun   = "re-run"
+	StatusPending = "pending"
+)
+
+// Metrics are the telemetry events sent around a step, empty names are not sent
+type Metrics struct {
+	Started   string
+	Completed string
+	Failed    string
+}
+
+// Step is a single unit of work in a Graph
+type Step struct {
+	// Name identifies the step in dependencies, progress and --from-step
+	Name        string
+	Description string
+	// DependsOn lists the steps that have to run before this one
+	DependsOn []string
+	// Check is the key under kubefirst-checks recording that the step completed,
+	// steps without a check run every time
+	Check string
+	// AlwaysRun runs the step even when its check is set, the check only records completion
+	AlwaysRun bool
+	// Condition decides whether the step runs instead of its check when set
+	Condition func() bool
+	Metrics   Metrics
+	Run       func(ctx context.Context) error
+	// Rollback undoes the step, it is optional
+	Rollback func(ctx context.Context) error
+	// UndoneBy names the step whose rollback also undoes this one, e.g. everything
+	// installed into a cluster is gone once the cluster is deleted
+	UndoneBy string
+	// RollbackAfter lists steps that have to be rolled back before this one, e.g. when
+	// their state is stored in what this step created
+	RollbackAfter []string
+}
+
+// Forget is a rollback for steps that only change the kubefirst config, undoing
+// them only clears their check so they run again
+func Forget(ctx context.Context) error {
+	return nil
+}
+
+// CheckKey returns the full viper key of the step check
+func (s Step) CheckKey() string {
+	if s.Check == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s.%s", checksKey, s.Check)
+}
+
+// completed reports whether the check of the step is set
+func (s Step) completed() bool {
+	return s.Check != "" && viper.GetBool(s.CheckKey())
+}
+
+// Error reports the step that failed a run
+type Error struct {
+	Step string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("step %s failed: %s", e.Step, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Plan describes what a run would do with a step
+type Plan struct {
+	Name        string `json:"name" yaml:"name"`
+	Check       string `json:"check" yaml:"check"`
+	Description string `json:"description" yaml:"description"`
+	Status      string `json:"status" yaml:"status"`
+}
+
+// RollbackReport lists the steps a rollback undid and the ones it could not
+type RollbackReport struct {
+	RolledBack []string `json:"rolled_back" yaml:"rolled_back"`
+	LeftOver   []string `json:"left_over" yaml:"left_over"`
+}
+
+// Observer is told about every step Run starts, completes and fails
+type Observer interface {
+	StepStarted(name string)
+	StepCompleted(name string)
+	StepFailed(name string, err error)
+}
+
+// Graph runs steps in dependency order, persisting their checks, sending their
+// telemetry and reporting their progress
+type Graph struct {
+	steps    []Step
+	index    map[string]int
+	teardown bool
+
+	segmentClient *telemetry.SegmentClient
+	observer      Observer
+	// ran holds the steps that completed during Run, in order
+	ran []string
+	// failed is the step that stopped Run
+	failed string
+}
+
+// NewGraph returns a graph that runs every step whose check is not set and sets it on success
+func NewGraph(segmentClient *telemetry.SegmentClient) *Graph {
+	return &Graph{
+		index:         map[string]int{},
+		segmentClient: segmentClient,
+	}
+}
+
+// NewTeardownGraph returns a graph that runs every step whose check is set and clears it on success
+func NewTeardownGraph(segmentClient *telemetry.SegmentClient) *Graph {
+	graph := NewGraph(segmentClient)
+	graph.teardown = true
+
+	return graph
+}
+
+// Observe reports the steps of every following Run to observer
+func (g *Graph) Observe(observer Observer) {
+	g.observer = observer
+}
+
+// Add appends steps to the graph, names have to be unique
+func (g *Graph) Add(steps ...Step) error {
+	for _, step := range steps {
+		if step.Name == "" {
+			return fmt.Errorf("step is missing a name")
+		}
+		if _, exists := g.index[step.Name]; exists {
+			return fmt.Errorf("step %s is defined more than once", step.Name)
+		}
+		if step.Run == nil {
+			return fmt.Errorf("step %s is missing a run function", step.Name)
+		}
+
+		g.index[step.Name] = len(g.steps)
+		g.steps = append(g.steps, step)
+	}
+
+	return nil
+}
+
+// Steps returns the steps in the order they run, a step runs after its dependencies
+// and otherwise keeps the position it was added at
+func (g *Graph) Steps() ([]Step, error) {
+	for _, step := range g.steps {
+		for _, dependency := range step.DependsOn {
+			if _, exists := g.index[dependency]; !exists {
+				return nil, fmt.Errorf("step %s depends on unknown step %s", step.Name, dependency)
+			}
+		}
+	}
+
+	ordered := make([]Step, 0, len(g.steps))
+	placed := map[string]bool{}
+	for len(ordered) < len(g.steps) {
+		progressed := false
+		for _, step := range g.steps {
+			if placed[step.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dependency := range step.DependsOn {
+				if !placed[dependency] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			placed[step.Name] = true
+			ordered = append(ordered, step)
+			progressed = true
+			// start over so earlier steps that were waiting on this one keep their position
+			break
+		}
+
+		if !progressed {
+			return nil, fmt.Errorf("steps have a circular dependency")
+		}
+	}
+
+	return ordered, nil
+}
+
+// Names returns the step names in run order
+func (g *Graph) Names() ([]string, error) {
+	ordered, err := g.Steps()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(ordered))
+	for _, step := range ordered {
+		names = append(names, step.Name)
+	}
+
+	return names, nil
+}
+
+// shouldRun decides whether a step has work to do
+func (g *Graph) shouldRun(step Step) bool {
+	if step.Condition != nil {
+		return step.Condition()
+	}
+	if step.Check == "" || step.AlwaysRun {
+		return true
+	}
+
+	// a teardown step only has work to do when what it removes was created
+	return step.completed() == g.teardown
+}
+
+// Run executes every step that has work to do, it stops at the first failure
+func (g *Graph) Run(ctx context.Context) error {
+	ordered, err := g.Steps()
+	if err != nil {
+		return err
+	}
+
+	for _, step := range ordered {
+		progressPrinter.AddTracker(step.Name, step.Description, 1)
+	}
+	progressPrinter.SetupProgress(progressPrinter.TotalOfTrackers(), false)
+
+	for _, step := range ordered {
+		if ctx.Err() != nil {
+			return &Error{Step: step.Name, Err: ctx.Err()}
+		}
+
+		if !g.shouldRun(step) {
+			if g.teardown {
+				log.Info().Msgf("nothing to tear down for step %s - continuing", step.Name)
+			} else {
+				log.Info().Msgf("already completed step %s - continuing", step.Name)
+			}
+			progressPrinter.IncrementTracker(step.Name, 1)
+			continue
+		}
+
+		log.Info().Msgf("running step %s", step.Name)
+		g.send(step.Metrics.Started, "")
+		if g.observer != nil {
+			g.observer.StepStarted(step.Name)
+		}
+
+		err := step.Run(ctx)
+		if err != nil {
+			g.failed = step.Name
+			g.send(step.Metrics.Failed, err.Error())
+			if g.observer != nil {
+				g.observer.StepFailed(step.Name, err)
+			}
+			return &Error{Step: step.Name, Err: err}
+		}
+
+		if step.Check != "" {
+			viper.Set(step.CheckKey(), !g.teardown)
+			viper.WriteConfig()
+		}
+		g.send(step.Metrics.Completed, "")
+		if g.observer != nil {
+			g.observer.StepCompleted(step.Name)
+		}
+		g.ran = append(g.ran, step.Name)
+		progressPrinter.IncrementTracker(step.Name, 1)
+	}
+
+	return nil
+}
+
+// Rollback undoes every completed step, last step first, and clears its check. The
+// step that failed Run is rolled back as well when it has a rollback function since
+// it may have left partial work behind. Completed steps that have neither a rollback
+// function nor a step undoing them, or whose rollback failed, are reported as left over
+func (g *Graph) Rollback(ctx context.Context) RollbackReport {
+	report := RollbackReport{}
+
+	ordered, err := g.Steps()
+	if err != nil {
+		log.Error().Msgf("unable to roll back: %s", err)
+		return report
+	}
+
+	candidates := []Step{}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		step := ordered[i]
+		if step.completed() || (step.Name == g.failed && step.Rollback != nil) {
+			candidates = append(candidates, step)
+		}
+	}
+
+	candidates, err = rollbackOrder(candidates)
+	if err != nil {
+		log.Error().Msgf("unable to roll back: %s", err)
+		return report
+	}
+
+	removed := map[string]bool{}
+	for _, step := range candidates {
+		if step.UndoneBy != "" || step.Rollback == nil {
+			continue
+		}
+
+		log.Info().Msgf("rolling back step %s", step.Name)
+		err := step.Rollback(ctx)
+		if err != nil {
+			log.Error().Msgf("error rolling back step %s: %s", step.Name, err)
+			continue
+		}
+		removed[step.Name] = true
+	}
+
+	for _, step := range candidates {
+		if !removed[step.Name] && !(step.UndoneBy != "" && removed[step.UndoneBy]) {
+			report.LeftOver = append(report.LeftOver, step.Name)
+			continue
+		}
+
+		if step.Check != "" {
+			viper.Set(step.CheckKey(), false)
+		}
+		report.RolledBack = append(report.RolledBack, step.Name)
+	}
+	viper.WriteConfig()
+
+	return report
+}
+
+// rollbackOrder keeps steps in the order given unless RollbackAfter requires otherwise
+func rollbackOrder(candidates []Step) ([]Step, error) {
+	pending := map[string]bool{}
+	for _, step := range candidates {
+		pending[step.Name] = true
+	}
+
+	ordered := make([]Step, 0, len(candidates))
+	for len(ordered) < len(candidates) {
+		progressed := false
+		for _, step := range candidates {
+			if !pending[step.Name] {
+				continue
+			}
+
+			ready := true
+			for _, name := range step.RollbackAfter {
+				if pending[name] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+
+			pending[step.Name] = false
+			ordered = append(ordered, step)
+			progressed = true
+			break
+		}
+
+		if !progressed {
+			return nil, fmt.Errorf("steps have a circular rollback order")
+		}
+	}
+
+	return ordered, nil
+}
+
+// Ran returns the steps that completed during Run, in order
+func (g *Graph) Ran() []string {
+	return g.ran
+}
+
+// indexOf returns the run position of a step by name
+func indexOf(ordered []Step, name string) (int, error) {
+	names := make([]string, 0, len(ordered))
+	for i, step := range ordered {
+		if step.Name == name {
+			return i, nil
+		}
+		if step.Check != "" {
+			names = append(names, step.Name)
+		}
+	}
+
+	return -1, fmt.Errorf("unknown step %q - must be one of: %s", name, strings.Join(names, ", "))
+}
+
+// Plan reports which checked or conditional steps would be skipped, re-run or are still
+// pending, treating every step from fromStep onwards as cleared when fromStep is set. The
+// condition of a step decides like it does in Run
+func (g *Graph) Plan(fromStep string) ([]Plan, error) {
+	ordered, err := g.Steps()
+	if err != nil {
+		return nil, err
+	}
+
+	fromIndex := len(ordered)
+	if fromStep != "" {
+		fromIndex, err = indexOf(ordered, fromStep)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan := []Plan{}
+	for i, step := range ordered {
+		if step.Check == "" && step.Condition == nil {
+			continue
+		}
+
+		completed := step.completed()
+
+		status := StatusPending
+		switch {
+		case step.Condition != nil && !step.Condition():
+			status = StatusSkip
+		case step.Condition != nil && completed:
+			status = StatusRerun
+		case step.Condition != nil:
+			status = StatusPending
+		case step.AlwaysRun && completed:
+			status = StatusRerun
+		case completed && i >= fromIndex:
+			status = StatusRerun
+		case completed:
+			status = StatusSkip
+		}
+
+		plan = append(plan, Plan{
+			Name:        step.Name,
+			Check:       step.CheckKey(),
+			Description: step.Description,
+			Status:      status,
+		})
+	}
+
+	return plan, nil
+}
+
+// ClearFrom resets the check of the named step and every step that runs after it
+func (g *Graph) ClearFrom(name string) error {
+	ordered, err := g.Steps()
+	if err != nil {
+		return err
+	}
+
+	fromIndex, err := indexOf(ordered, name)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range ordered[fromIndex:] {
+		if step.Check != "" {
+			viper.Set(step.CheckKey(), false)
+		}
+	}
+
+	return viper.WriteConfig()
+}
+
+func (g *Graph) send(metricName string, errMsg string) {
+	if g.segmentClient == nil || metricName == "" {
+		return
+	}
+
+	telemetry.SendEvent(g.segmentClient, metricName, errMsg)
+}
@@ -0,0 +1,648 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package steps
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubefirst/runtime/pkg/progressPrinter"
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	// Run reports to the progress printer, which has to exist before it is set up
+	progressPrinter.GetInstance()
+	os.Exit(m.Run())
+}
+
+// useTestConfig points viper at a kubefirst config in a temporary directory with the
+// given checks set
+func useTestConfig(t *testing.T, checks ...string) {
+	configPath := filepath.Join(t.TempDir(), ".kubefirst")
+	err := os.WriteFile(configPath, []byte("{}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(configPath)
+	viper.SetConfigType("yaml")
+	t.Cleanup(viper.Reset)
+
+	for _, check := range checks {
+		viper.Set(Step{Check: check}.CheckKey(), true)
+	}
+}
+
+// fakeStep returns a step that records its name in ran when it runs
+func fakeStep(ran *[]string, name string, dependsOn ...string) Step {
+	return Step{
+		Name:      name,
+		DependsOn: dependsOn,
+		Check:     name + "-check",
+		Run: func(ctx context.Context) error {
+			*ran = append(*ran, name)
+			return nil
+		},
+	}
+}
+
+// newTestGraph returns a graph of steps, failing the test when they can't be added
+func newTestGraph(t *testing.T, teardown bool, steps ...Step) *Graph {
+	graph := NewGraph(nil)
+	if teardown {
+		graph = NewTeardownGraph(nil)
+	}
+	err := graph.Add(steps...)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	return graph
+}
+
+func TestGraphAdd(t *testing.T) {
+	run := func(ctx context.Context) error { return nil }
+
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantErr bool
+	}{
+		{
+			name:  "unique steps",
+			steps: []Step{{Name: "a", Run: run}, {Name: "b", Run: run}},
+		},
+		{
+			name:    "missing name",
+			steps:   []Step{{Run: run}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			steps:   []Step{{Name: "a", Run: run}, {Name: "a", Run: run}},
+			wantErr: true,
+		},
+		{
+			name:    "missing run function",
+			steps:   []Step{{Name: "a"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewGraph(nil).Add(tt.steps...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Add() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGraphNames(t *testing.T) {
+	ran := []string{}
+
+	tests := []struct {
+		name    string
+		steps   []Step
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "independent steps keep the order they were added in",
+			steps: []Step{fakeStep(&ran, "a"), fakeStep(&ran, "b"), fakeStep(&ran, "c")},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "step runs after a dependency added later",
+			steps: []Step{fakeStep(&ran, "a", "c"), fakeStep(&ran, "b"), fakeStep(&ran, "c")},
+			want:  []string{"b", "c", "a"},
+		},
+		{
+			name: "chain of dependencies",
+			steps: []Step{
+				fakeStep(&ran, "a", "b"),
+				fakeStep(&ran, "b", "c"),
+				fakeStep(&ran, "c"),
+			},
+			want: []string{"c", "b", "a"},
+		},
+		{
+			name: "step waiting on a dependency keeps its position among the others",
+			steps: []Step{
+				fakeStep(&ran, "a"),
+				fakeStep(&ran, "b", "d"),
+				fakeStep(&ran, "c"),
+				fakeStep(&ran, "d", "a"),
+			},
+			want: []string{"a", "c", "d", "b"},
+		},
+		{
+			name:    "unknown dependency",
+			steps:   []Step{fakeStep(&ran, "a", "missing")},
+			wantErr: true,
+		},
+		{
+			name:    "circular dependency",
+			steps:   []Step{fakeStep(&ran, "a", "b"), fakeStep(&ran, "b", "a")},
+			wantErr: true,
+		},
+		{
+			name:    "step depending on itself",
+			steps:   []Step{fakeStep(&ran, "a", "a")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := newTestGraph(t, false, tt.steps...).Names()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Names() error = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Names() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphRun(t *testing.T) {
+	never := func() bool { return false }
+	always := func() bool { return true }
+
+	tests := []struct {
+		name     string
+		teardown bool
+		// checks are set before the run
+		checks []string
+		// configure adjusts the steps a, b and c before they are added
+		configure  func(a, b, c *Step)
+		wantRan    []string
+		wantChecks map[string]bool
+	}{
+		{
+			name:       "runs every step and sets its check",
+			wantRan:    []string{"a", "b", "c"},
+			wantChecks: map[string]bool{"a": true, "b": true, "c": true},
+		},
+		{
+			name:       "skips completed steps",
+			checks:     []string{"a-check", "b-check"},
+			wantRan:    []string{"c"},
+			wantChecks: map[string]bool{"a": true, "b": true, "c": true},
+		},
+		{
+			name:   "runs steps without a check every time",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				b.Check = ""
+			},
+			wantRan: []string{"b"},
+		},
+		{
+			name:   "always runs a completed step that should",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				a.AlwaysRun = true
+			},
+			wantRan:    []string{"a"},
+			wantChecks: map[string]bool{"a": true},
+		},
+		{
+			name: "condition skips a step that was never run",
+			configure: func(a, b, c *Step) {
+				b.Condition = never
+			},
+			wantRan:    []string{"a", "c"},
+			wantChecks: map[string]bool{"a": true, "b": false, "c": true},
+		},
+		{
+			name:   "condition runs a completed step",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				c.Condition = always
+			},
+			wantRan: []string{"c"},
+		},
+		{
+			name:       "teardown runs only completed steps and clears their checks",
+			teardown:   true,
+			checks:     []string{"a-check", "c-check"},
+			wantRan:    []string{"a", "c"},
+			wantChecks: map[string]bool{"a": false, "b": false, "c": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestConfig(t, tt.checks...)
+
+			ran := []string{}
+			a, b, c := fakeStep(&ran, "a"), fakeStep(&ran, "b", "a"), fakeStep(&ran, "c", "b")
+			if tt.configure != nil {
+				tt.configure(&a, &b, &c)
+			}
+			graph := newTestGraph(t, tt.teardown, a, b, c)
+
+			err := graph.Run(context.Background())
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !reflect.DeepEqual(ran, tt.wantRan) {
+				t.Errorf("Run() ran %v, want %v", ran, tt.wantRan)
+			}
+			if !reflect.DeepEqual(graph.Ran(), tt.wantRan) {
+				t.Errorf("Ran() = %v, want %v", graph.Ran(), tt.wantRan)
+			}
+			for name, want := range tt.wantChecks {
+				if got := viper.GetBool(Step{Check: name + "-check"}.CheckKey()); got != want {
+					t.Errorf("check of step %s = %t, want %t", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// recorder is an Observer keeping every event it is told about
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) StepStarted(name string)           { r.events = append(r.events, "started "+name) }
+func (r *recorder) StepCompleted(name string)         { r.events = append(r.events, "completed "+name) }
+func (r *recorder) StepFailed(name string, err error) { r.events = append(r.events, "failed "+name) }
+
+func TestGraphRunStopsAtFailure(t *testing.T) {
+	useTestConfig(t)
+
+	failure := errors.New("boom")
+	ran := []string{}
+	b := fakeStep(&ran, "b", "a")
+	b.Run = func(ctx context.Context) error { return failure }
+	graph := newTestGraph(t, false, fakeStep(&ran, "a"), b, fakeStep(&ran, "c", "b"))
+	observer := &recorder{}
+	graph.Observe(observer)
+
+	err := graph.Run(context.Background())
+
+	var stepErr *Error
+	if !errors.As(err, &stepErr) || stepErr.Step != "b" {
+		t.Fatalf("Run() error = %v, want a failure of step b", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("Run() error = %v, want it to wrap %v", err, failure)
+	}
+	if !reflect.DeepEqual(ran, []string{"a"}) {
+		t.Errorf("Run() ran %v, want only a", ran)
+	}
+	if viper.GetBool("kubefirst-checks.b-check") {
+		t.Error("check of the failed step is set")
+	}
+	wantEvents := []string{"started a", "completed a", "started b", "failed b"}
+	if !reflect.DeepEqual(observer.events, wantEvents) {
+		t.Errorf("observed %v, want %v", observer.events, wantEvents)
+	}
+}
+
+func TestRollbackOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "keeps the given order",
+			steps: []Step{{Name: "c"}, {Name: "b"}, {Name: "a"}},
+			want:  []string{"c", "b", "a"},
+		},
+		{
+			name:  "rolls back after the named steps",
+			steps: []Step{{Name: "c", RollbackAfter: []string{"a"}}, {Name: "b"}, {Name: "a"}},
+			want:  []string{"b", "a", "c"},
+		},
+		{
+			name:  "ignores steps that are not rolled back",
+			steps: []Step{{Name: "c", RollbackAfter: []string{"missing"}}, {Name: "b"}},
+			want:  []string{"c", "b"},
+		},
+		{
+			name:    "circular order",
+			steps:   []Step{{Name: "b", RollbackAfter: []string{"a"}}, {Name: "a", RollbackAfter: []string{"b"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := rollbackOrder(tt.steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rollbackOrder() error = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			names := []string{}
+			for _, step := range ordered {
+				names = append(names, step.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("rollbackOrder() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphRollback(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []string
+		// configure adjusts the steps a, b and c before they are added
+		configure      func(a, b, c *Step)
+		wantRolledBack []string
+		wantLeftOver   []string
+		wantUndone     []string
+	}{
+		{
+			name:           "rolls back completed steps last first",
+			checks:         []string{"a-check", "b-check", "c-check"},
+			wantRolledBack: []string{"c", "b", "a"},
+			wantUndone:     []string{"c", "b", "a"},
+		},
+		{
+			name:           "leaves steps that never completed alone",
+			checks:         []string{"a-check"},
+			wantRolledBack: []string{"a"},
+			wantUndone:     []string{"a"},
+		},
+		{
+			name:   "step undone by another is not rolled back itself",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				b.UndoneBy = "a"
+				c.UndoneBy = "a"
+			},
+			wantRolledBack: []string{"c", "b", "a"},
+			wantUndone:     []string{"a"},
+		},
+		{
+			name:   "rolls back after the named steps",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				c.RollbackAfter = []string{"a"}
+			},
+			wantRolledBack: []string{"b", "a", "c"},
+			wantUndone:     []string{"b", "a", "c"},
+		},
+		{
+			name:   "step without a rollback is left over",
+			checks: []string{"a-check", "b-check"},
+			configure: func(a, b, c *Step) {
+				b.Rollback = nil
+			},
+			wantRolledBack: []string{"a"},
+			wantLeftOver:   []string{"b"},
+			wantUndone:     []string{"a"},
+		},
+		{
+			name:   "failed rollback is left over",
+			checks: []string{"a-check", "b-check", "c-check"},
+			configure: func(a, b, c *Step) {
+				a.Rollback = func(ctx context.Context) error { return errors.New("boom") }
+				b.UndoneBy = "a"
+			},
+			wantRolledBack: []string{"c"},
+			wantLeftOver:   []string{"b", "a"},
+			wantUndone:     []string{"c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestConfig(t, tt.checks...)
+
+			ran, undone := []string{}, []string{}
+			rollback := func(name string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					undone = append(undone, name)
+					return nil
+				}
+			}
+			a, b, c := fakeStep(&ran, "a"), fakeStep(&ran, "b", "a"), fakeStep(&ran, "c", "b")
+			a.Rollback, b.Rollback, c.Rollback = rollback("a"), rollback("b"), rollback("c")
+			if tt.configure != nil {
+				tt.configure(&a, &b, &c)
+			}
+
+			report := newTestGraph(t, false, a, b, c).Rollback(context.Background())
+
+			if !reflect.DeepEqual(report.RolledBack, tt.wantRolledBack) {
+				t.Errorf("rolled back %v, want %v", report.RolledBack, tt.wantRolledBack)
+			}
+			if !reflect.DeepEqual(report.LeftOver, tt.wantLeftOver) {
+				t.Errorf("left over %v, want %v", report.LeftOver, tt.wantLeftOver)
+			}
+			if !reflect.DeepEqual(undone, tt.wantUndone) {
+				t.Errorf("ran the rollback of %v, want %v", undone, tt.wantUndone)
+			}
+			for _, name := range report.RolledBack {
+				if viper.GetBool(Step{Check: name + "-check"}.CheckKey()) {
+					t.Errorf("check of rolled back step %s is still set", name)
+				}
+			}
+			for _, name := range report.LeftOver {
+				if !viper.GetBool(Step{Check: name + "-check"}.CheckKey()) {
+					t.Errorf("check of left over step %s was cleared", name)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphRollbackIncludesFailedStep(t *testing.T) {
+	useTestConfig(t)
+
+	ran, undone := []string{}, []string{}
+	a := fakeStep(&ran, "a")
+	a.Rollback = func(ctx context.Context) error {
+		undone = append(undone, "a")
+		return nil
+	}
+	b := fakeStep(&ran, "b", "a")
+	b.Run = func(ctx context.Context) error { return errors.New("boom") }
+	b.Rollback = func(ctx context.Context) error {
+		undone = append(undone, "b")
+		return nil
+	}
+	graph := newTestGraph(t, false, a, b)
+
+	if err := graph.Run(context.Background()); err == nil {
+		t.Fatal("Run() succeeded, want the failure of step b")
+	}
+	report := graph.Rollback(context.Background())
+
+	if !reflect.DeepEqual(undone, []string{"b", "a"}) {
+		t.Errorf("ran the rollback of %v, want the failed step first", undone)
+	}
+	if len(report.LeftOver) != 0 {
+		t.Errorf("left over %v, want nothing", report.LeftOver)
+	}
+}
+
+func TestGraphPlan(t *testing.T) {
+	never := func() bool { return false }
+	always := func() bool { return true }
+
+	tests := []struct {
+		name     string
+		checks   []string
+		fromStep string
+		// configure adjusts the steps a, b and c before they are added
+		configure func(a, b, c *Step)
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:   "completed steps are skipped",
+			checks: []string{"a-check"},
+			want:   map[string]string{"a": StatusSkip, "b": StatusPending, "c": StatusPending},
+		},
+		{
+			name:     "completed steps from the given one are re-run",
+			checks:   []string{"a-check", "b-check", "c-check"},
+			fromStep: "b",
+			want:     map[string]string{"a": StatusSkip, "b": StatusRerun, "c": StatusRerun},
+		},
+		{
+			name:   "completed steps that always run are re-run",
+			checks: []string{"a-check", "b-check"},
+			configure: func(a, b, c *Step) {
+				a.AlwaysRun = true
+			},
+			want: map[string]string{"a": StatusRerun, "b": StatusSkip, "c": StatusPending},
+		},
+		{
+			name:   "condition decides instead of the check",
+			checks: []string{"a-check"},
+			configure: func(a, b, c *Step) {
+				a.Condition = always
+				b.Condition = always
+				c.Condition = never
+			},
+			want: map[string]string{"a": StatusRerun, "b": StatusPending, "c": StatusSkip},
+		},
+		{
+			name: "steps without a check or condition are left out",
+			configure: func(a, b, c *Step) {
+				b.Check = ""
+			},
+			want: map[string]string{"a": StatusPending, "c": StatusPending},
+		},
+		{
+			name:     "unknown step",
+			fromStep: "missing",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestConfig(t, tt.checks...)
+
+			ran := []string{}
+			a, b, c := fakeStep(&ran, "a"), fakeStep(&ran, "b", "a"), fakeStep(&ran, "c", "b")
+			a.Description = "step a"
+			if tt.configure != nil {
+				tt.configure(&a, &b, &c)
+			}
+
+			plan, err := newTestGraph(t, false, a, b, c).Plan(tt.fromStep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Plan() error = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := map[string]string{}
+			for _, step := range plan {
+				got[step.Name] = step.Status
+				if step.Name == "a" && (step.Check != "kubefirst-checks.a-check" || step.Description != "step a") {
+					t.Errorf("Plan() step a = %+v, want its check key and description", step)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Plan() = %v, want %v", got, tt.want)
+			}
+			if len(ran) != 0 {
+				t.Errorf("Plan() ran %v, want nothing", ran)
+			}
+		})
+	}
+}
+
+func TestGraphClearFrom(t *testing.T) {
+	tests := []struct {
+		name      string
+		fromStep  string
+		wantSet   []string
+		wantClear []string
+		wantErr   bool
+	}{
+		{
+			name:      "clears the step and every one after it",
+			fromStep:  "b",
+			wantSet:   []string{"a"},
+			wantClear: []string{"b", "c"},
+		},
+		{
+			name:      "clears in run order, not in the order steps were added",
+			fromStep:  "c",
+			wantSet:   []string{"a", "b"},
+			wantClear: []string{"c"},
+		},
+		{
+			name:     "unknown step",
+			fromStep: "missing",
+			wantSet:  []string{"a", "b", "c"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestConfig(t, "a-check", "b-check", "c-check")
+
+			ran := []string{}
+			// c is added first but runs last
+			graph := newTestGraph(t, false, fakeStep(&ran, "c", "b"), fakeStep(&ran, "a"), fakeStep(&ran, "b", "a"))
+
+			err := graph.ClearFrom(tt.fromStep)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ClearFrom() error = %v, want error %t", err, tt.wantErr)
+			}
+			for _, name := range tt.wantSet {
+				if !viper.GetBool(Step{Check: name + "-check"}.CheckKey()) {
+					t.Errorf("check of step %s was cleared", name)
+				}
+			}
+			for _, name := range tt.wantClear {
+				if viper.GetBool(Step{Check: name + "-check"}.CheckKey()) {
+					t.Errorf("check of step %s is still set", name)
+				}
+			}
+		})
+	}
+}
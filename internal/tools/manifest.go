@@ -0,0 +1,120 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package tools
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lockFile records the version and checksum of every tool installed in a tools directory
+const lockFile = ".kubefirst-tools.json"
+
+// Platforms are the os and architecture pairs the manifest pins checksums for
+var Platforms = []string{"darwin/amd64", "darwin/arm64", "linux/amd64", "linux/arm64"}
+
+// manifestContent pins the version of every tool and the sha256 of its binary per
+// platform, regenerate the checksums with `kubefirst tools manifest` after a version bump
+//
+//go:embed manifest.json
+var manifestContent []byte
+
+// Pin is the version of a tool and the sha256 of its binary keyed by os/arch
+type Pin struct {
+	Version string            `json:"version"`
+	SHA256  map[string]string `json:"sha256"`
+}
+
+// Manifest pins every tool kubefirst downloads
+type Manifest struct {
+	Tools map[string]Pin `json:"tools"`
+}
+
+var manifest = loadManifest()
+
+func loadManifest() Manifest {
+	m := Manifest{}
+	err := json.Unmarshal(manifestContent, &m)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded tool manifest: %s", err))
+	}
+
+	return m
+}
+
+// pinned returns the version of a tool from the manifest
+func pinned(name string) string {
+	pin, exists := manifest.Tools[name]
+	if !exists {
+		panic(fmt.Sprintf("tool %s is missing from the embedded tool manifest", name))
+	}
+
+	return pin.Version
+}
+
+// lockEntry is an installed tool
+type lockEntry struct {
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+func readLock(toolsDir string) map[string]lockEntry {
+	lock := map[string]lockEntry{}
+
+	content, err := os.ReadFile(filepath.Join(toolsDir, lockFile))
+	if err != nil {
+		return lock
+	}
+	// an unreadable lock only means every tool is downloaded again
+	_ = json.Unmarshal(content, &lock)
+
+	return lock
+}
+
+func writeLock(toolsDir string, lock map[string]lockEntry) error {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(toolsDir, lockFile), content, 0644)
+}
+
+// GenerateManifest downloads every tool for every platform and returns a manifest
+// with the checksum of each binary
+func GenerateManifest() (*Manifest, error) {
+	generated := &Manifest{Tools: map[string]Pin{}}
+
+	workDir, err := os.MkdirTemp("", "kubefirst-tools-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	for _, tool := range All {
+		pin := Pin{Version: tool.Version, SHA256: map[string]string{}}
+		for _, platform := range Platforms {
+			goos, goarch, _ := strings.Cut(platform, "/")
+			binaryPath, err := tool.fetch(goos, goarch, filepath.Join(workDir, tool.Name, goos, goarch))
+			if err != nil {
+				return nil, err
+			}
+			sum, err := checksum(binaryPath)
+			if err != nil {
+				return nil, err
+			}
+			pin.SHA256[platform] = sum
+		}
+		generated.Tools[tool.Name] = pin
+	}
+
+	return generated, nil
+}
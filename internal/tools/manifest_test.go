@@ -0,0 +1,63 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package tools
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// TestManifestPinsEveryPlatform fails when a tool has no valid sha256 for a platform, a
+// missing pin makes every download of the tool fail on that platform
+func TestManifestPinsEveryPlatform(t *testing.T) {
+	for _, tool := range All {
+		pin, exists := manifest.Tools[tool.Name]
+		if !exists {
+			t.Errorf("%s is missing from the tool manifest", tool.Name)
+			continue
+		}
+		if pin.Version == "" {
+			t.Errorf("%s has no version in the tool manifest", tool.Name)
+		}
+
+		for _, platform := range Platforms {
+			sum := pin.SHA256[platform]
+			if sum == "" {
+				t.Errorf("%s %s has no sha256 for %s - regenerate the manifest with `kubefirst tools manifest`", tool.Name, pin.Version, platform)
+				continue
+			}
+			decoded, err := hex.DecodeString(sum)
+			if err != nil || len(decoded) != 32 {
+				t.Errorf("%s %s has an invalid sha256 for %s: %q", tool.Name, pin.Version, platform, sum)
+			}
+		}
+
+		for platform := range pin.SHA256 {
+			if !slices.Contains(Platforms, platform) {
+				t.Errorf("%s pins a sha256 for %s, which is not one of %s", tool.Name, platform, Platforms)
+			}
+		}
+	}
+
+	for name := range manifest.Tools {
+		if !isTool(name) {
+			t.Errorf("the tool manifest pins %s, which kubefirst does not download", name)
+		}
+	}
+}
+
+func isTool(name string) bool {
+	for _, tool := range All {
+		if tool.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
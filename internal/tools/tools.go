@@ -0,0 +1,330 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubefirst/runtime/pkg/downloadManager"
+	"github.com/kubefirst/runtime/pkg/k3d"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// Tool is a binary kubefirst downloads into a tools directory
+type Tool struct {
+	Name    string
+	Version string
+	// url returns the download location for an os and architecture
+	url func(tool Tool, goos string, goarch string) string
+	// sumsURL returns the checksum file the project publishes for the download, it is
+	// nil when the project publishes none
+	sumsURL func(tool Tool, goos string, goarch string) string
+	// archive is the format of the download, empty for a plain binary
+	archive string
+	// archivePath returns the path of the binary inside a tar.gz download
+	archivePath func(goos string, goarch string) string
+}
+
+var (
+	K3d = Tool{
+		Name:    "k3d",
+		Version: pinned("k3d"),
+		url: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://github.com/k3d-io/k3d/releases/download/%s/k3d-%s-%s", tool.Version, goos, goarch)
+		},
+	}
+	Kubectl = Tool{
+		Name:    "kubectl",
+		Version: pinned("kubectl"),
+		url: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://dl.k8s.io/release/%s/bin/%s/%s/kubectl", tool.Version, goos, goarch)
+		},
+		sumsURL: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://dl.k8s.io/release/%s/bin/%s/%s/kubectl.sha256", tool.Version, goos, goarch)
+		},
+	}
+	Mkcert = Tool{
+		Name:    "mkcert",
+		Version: pinned("mkcert"),
+		url: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://github.com/FiloSottile/mkcert/releases/download/%s/mkcert-%s-%s-%s", tool.Version, tool.Version, goos, goarch)
+		},
+	}
+	Terraform = Tool{
+		Name:    "terraform",
+		Version: pinned("terraform"),
+		url: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s_%s_%s.zip", tool.Version, tool.Version, goos, goarch)
+		},
+		sumsURL: func(tool Tool, goos string, goarch string) string {
+			return fmt.Sprintf("https://releases.hashicorp.com/terraform/%s/terraform_%s_SHA256SUMS", tool.Version, tool.Version)
+		},
+		archive: archiveZip,
+	}
+	// All lists every tool kubefirst downloads, launch uses k3d and mkcert while a k3d
+	// installation uses all of them
+	All = []Tool{K3d, Kubectl, Mkcert, Terraform}
+)
+
+// URL returns the download location of the tool for the local os and architecture
+func (t Tool) URL() string {
+	return t.url(t, k3d.LocalhostOS, k3d.LocalhostARCH)
+}
+
+// Path returns the location of the tool in a tools directory
+func (t Tool) Path(toolsDir string) string {
+	return fmt.Sprintf("%s/%s", toolsDir, t.Name)
+}
+
+// Checksum returns the pinned sha256 of the binary for the local os and architecture,
+// it is empty when the manifest has no pin for the platform
+func (t Tool) Checksum() string {
+	return manifest.Tools[t.Name].SHA256[fmt.Sprintf("%s/%s", k3d.LocalhostOS, k3d.LocalhostARCH)]
+}
+
+// Installed reports whether the tool is present in toolsDir
+func (t Tool) Installed(toolsDir string) bool {
+	_, err := os.Stat(t.Path(toolsDir))
+	return err == nil
+}
+
+// Current reports whether toolsDir holds the pinned version of the tool and the binary
+// still matches its pinned checksum
+func (t Tool) Current(toolsDir string) bool {
+	if !t.Installed(toolsDir) {
+		return false
+	}
+
+	installed, exists := readLock(toolsDir)[t.Name]
+	if !exists {
+		log.Info().Msgf("%s in %s was not installed with a version record, replacing it", t.Name, toolsDir)
+		return false
+	}
+	if installed.Version != t.Version {
+		log.Info().Msgf("upgrading %s from %s to %s", t.Name, installed.Version, t.Version)
+		return false
+	}
+
+	err := t.verify(t.Path(toolsDir))
+	if err != nil {
+		log.Warn().Msgf("%s in %s can't be verified, replacing it: %s", t.Name, toolsDir, err)
+		return false
+	}
+
+	return true
+}
+
+// Ensure downloads the tool into toolsDir unless the current version is already there
+func (t Tool) Ensure(toolsDir string) error {
+	if t.Current(toolsDir) {
+		log.Info().Msgf("%s %s is already installed, continuing", t.Name, t.Version)
+		return nil
+	}
+
+	return t.Download(toolsDir)
+}
+
+// Download fetches the tool for the local os and architecture into toolsDir, verifying
+// it against the published checksum of the download where there is one and always
+// against the pinned checksum of the binary
+func (t Tool) Download(toolsDir string) error {
+	log.Info().Msgf("Downloading %s %s...", t.Name, t.Version)
+
+	err := os.MkdirAll(toolsDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	staging, err := os.MkdirTemp(toolsDir, fmt.Sprintf(".%s-", t.Name))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	binaryPath, err := t.fetch(k3d.LocalhostOS, k3d.LocalhostARCH, staging)
+	if err != nil {
+		return fmt.Errorf("error while trying to download %s: %s", t.Name, err)
+	}
+
+	err = t.verify(binaryPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(binaryPath, t.Path(toolsDir))
+	if err != nil {
+		return err
+	}
+
+	return t.Register(toolsDir)
+}
+
+// Register verifies a binary placed in toolsDir against its pinned checksum and records
+// its version and checksum so later runs can tell whether it is current
+func (t Tool) Register(toolsDir string) error {
+	binaryPath := t.Path(toolsDir)
+
+	err := t.verify(binaryPath)
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(binaryPath, 0755)
+	if err != nil {
+		return err
+	}
+
+	sum, err := checksum(binaryPath)
+	if err != nil {
+		return err
+	}
+	lock := readLock(toolsDir)
+	lock[t.Name] = lockEntry{Version: t.Version, SHA256: sum}
+
+	return writeLock(toolsDir, lock)
+}
+
+// verify compares a binary with the pinned checksum for the local platform, a binary
+// without a pin is never trusted
+func (t Tool) verify(binaryPath string) error {
+	expected := t.Checksum()
+	if expected == "" {
+		return fmt.Errorf("no sha256 is pinned for %s %s on %s/%s in the tool manifest - regenerate it with `kubefirst tools manifest`", t.Name, t.Version, k3d.LocalhostOS, k3d.LocalhostARCH)
+	}
+
+	sum, err := checksum(binaryPath)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum mismatch for %s %s on %s/%s: expected %s, got %s", t.Name, t.Version, k3d.LocalhostOS, k3d.LocalhostARCH, expected, sum)
+	}
+
+	return nil
+}
+
+// fetch downloads the tool for a platform into dir, verifies the download against the
+// checksum the project publishes and returns the path of the extracted binary
+func (t Tool) fetch(goos string, goarch string, dir string) (string, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	url := t.url(t, goos, goarch)
+	downloadPath := filepath.Join(dir, path.Base(url))
+	err = downloadManager.DownloadFile(downloadPath, url)
+	if err != nil {
+		return "", err
+	}
+
+	if t.sumsURL != nil {
+		err = verifyPublishedChecksum(downloadPath, t.sumsURL(t, goos, goarch))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	binaryPath := filepath.Join(dir, t.Name)
+	switch t.archive {
+	case archiveTarGz:
+		archive, err := os.Open(downloadPath)
+		if err != nil {
+			return "", err
+		}
+		downloadManager.ExtractFileFromTarGz(archive, t.archivePath(goos, goarch), binaryPath)
+		archive.Close()
+		os.Remove(downloadPath)
+	case archiveZip:
+		err = downloadManager.Unzip(downloadPath, dir)
+		if err != nil {
+			return "", err
+		}
+		os.Remove(downloadPath)
+	default:
+		err = os.Rename(downloadPath, binaryPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := os.Stat(binaryPath); err != nil {
+		return "", fmt.Errorf("%s was not found in %s", t.Name, url)
+	}
+
+	return binaryPath, nil
+}
+
+// verifyPublishedChecksum compares a download with its entry in a checksum file, which
+// either holds a single checksum or `<checksum>  <file>` lines
+func verifyPublishedChecksum(downloadPath string, sumsURL string) error {
+	resp, err := http.Get(sumsURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download checksums from %s, the HTTP return status is: %s", sumsURL, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(downloadPath)
+	expected := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1:
+			expected = fields[0]
+		case len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name:
+			expected = fields[0]
+		}
+	}
+	if expected == "" {
+		return fmt.Errorf("%s has no checksum for %s", sumsURL, name)
+	}
+
+	sum, err := checksum(downloadPath)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum mismatch for %s: %s publishes %s, got %s", name, sumsURL, expected, sum)
+	}
+
+	return nil
+}
+
+// checksum returns the hex encoded sha256 of a file
+func checksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
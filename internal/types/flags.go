@@ -15,6 +15,8 @@ type CliFlags struct {
 	ClusterType          string
 	DnsProvider          string
 	DomainName           string
+	EventsFile           string
+	EventsFormat         string
 	GitProvider          string
 	GitProtocol          string
 	GithubOrg            string
@@ -7,13 +7,20 @@ See the LICENSE file for more details.
 package utilities
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/kubefirst/kubefirst/internal/gitShim"
 	"github.com/kubefirst/kubefirst/internal/progress"
 	"github.com/kubefirst/kubefirst/internal/types"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/exp/slices"
 )
 
 func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error) {
@@ -55,6 +62,23 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 	}
 	githubOrgFlag = strings.ToLower(githubOrgFlag)
 
+	eventsFileFlag, err := cmd.Flags().GetString("events-file")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, err
+	}
+
+	eventsFormatFlag, err := cmd.Flags().GetString("events-format")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, err
+	}
+	if eventsFormatFlag != progress.EventsFormatNDJSON {
+		err = fmt.Errorf("invalid events format %q, the only supported format is %s", eventsFormatFlag, progress.EventsFormatNDJSON)
+		progress.Error(err.Error())
+		return cliFlags, err
+	}
+
 	gitlabGroupFlag, err := cmd.Flags().GetString("gitlab-group")
 	if err != nil {
 		progress.Error(err.Error())
@@ -67,6 +91,11 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 		progress.Error(err.Error())
 		return cliFlags, err
 	}
+	err = validateClusterGitProvider(gitProviderFlag)
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, err
+	}
 
 	gitProtocolFlag, err := cmd.Flags().GetString("git-protocol")
 	if err != nil {
@@ -117,6 +146,8 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 	cliFlags.ClusterName = clusterNameFlag
 	cliFlags.DnsProvider = dnsProviderFlag
 	cliFlags.DomainName = domainNameFlag
+	cliFlags.EventsFile = eventsFileFlag
+	cliFlags.EventsFormat = eventsFormatFlag
 	cliFlags.GitProtocol = gitProtocolFlag
 	cliFlags.GitProvider = gitProviderFlag
 	cliFlags.GithubOrg = githubOrgFlag
@@ -138,3 +169,195 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 
 	return cliFlags, nil
 }
+
+// validateClusterGitProvider refuses git providers the kubefirst api can't create a cluster
+// with, the cluster definition it accepts only names github and gitlab
+func validateClusterGitProvider(gitProvider string) error {
+	if !slices.Contains(gitShim.SupportedGitProviders, gitProvider) {
+		return fmt.Errorf("invalid git provider %q, --git-provider must be one of: %s", gitProvider, gitShim.SupportedGitProviders)
+	}
+
+	return nil
+}
+
+// getConfigurableFlag reads a string flag, the value of the same key under flags in the
+// kubefirst config is used when the flag is not set on the command line
+func getConfigurableFlag(cmd *cobra.Command, name string) (string, error) {
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return "", err
+	}
+
+	configKey := fmt.Sprintf("flags.%s", name)
+	if !cmd.Flags().Changed(name) && viper.GetString(configKey) != "" {
+		value = viper.GetString(configKey)
+	}
+
+	return value, nil
+}
+
+// The names of the repositories and teams kubefirst creates when no name flag is set
+const (
+	DefaultGitopsRepoName    = "gitops"
+	DefaultMetaphorRepoName  = "metaphor"
+	DefaultAdminTeamName     = "admins"
+	DefaultDeveloperTeamName = "developers"
+)
+
+// GitNames are the names of the repositories and teams kubefirst creates in the git provider,
+// every name already carries the prefix
+type GitNames struct {
+	Prefix        string
+	GitopsRepo    string
+	MetaphorRepo  string
+	AdminTeam     string
+	DeveloperTeam string
+}
+
+// DefaultGitNames are the names kubefirst uses without a prefix or name flag
+var DefaultGitNames = GitNames{
+	GitopsRepo:    DefaultGitopsRepoName,
+	MetaphorRepo:  DefaultMetaphorRepoName,
+	AdminTeam:     DefaultAdminTeamName,
+	DeveloperTeam: DefaultDeveloperTeamName,
+}
+
+// Repositories are the gitops and metaphor repository names
+func (n GitNames) Repositories() []string {
+	return []string{n.GitopsRepo, n.MetaphorRepo}
+}
+
+// Teams are the admin and developer team names
+func (n GitNames) Teams() []string {
+	return []string{n.AdminTeam, n.DeveloperTeam}
+}
+
+// terraformVariables maps the variables of the gitops template git terraform to the names
+func (n GitNames) terraformVariables() map[string]string {
+	return map[string]string{
+		"gitops_repo_name":    n.GitopsRepo,
+		"metaphor_repo_name":  n.MetaphorRepo,
+		"admin_team_name":     n.AdminTeam,
+		"developer_team_name": n.DeveloperTeam,
+	}
+}
+
+// TerraformEnvs passes the names to the git terraform as TF_VAR_ environment variables
+func (n GitNames) TerraformEnvs() map[string]string {
+	tfEnvs := map[string]string{}
+	for variable, name := range n.terraformVariables() {
+		tfEnvs[fmt.Sprintf("TF_VAR_%s", variable)] = name
+	}
+
+	return tfEnvs
+}
+
+// terraformVariablePattern matches the variable declarations of a terraform file
+var terraformVariablePattern = regexp.MustCompile(`(?m)^\s*variable\s+"([^"]+)"`)
+
+// CheckTerraformVariables verifies that the terraform module at tfEntrypoint declares every
+// name variable, terraform ignores TF_VAR_ environment variables it does not declare
+func (n GitNames) CheckTerraformVariables(tfEntrypoint string) error {
+	files, err := filepath.Glob(filepath.Join(tfEntrypoint, "*.tf"))
+	if err != nil {
+		return err
+	}
+
+	declared := map[string]bool{}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for _, match := range terraformVariablePattern.FindAllSubmatch(content, -1) {
+			declared[string(match[1])] = true
+		}
+	}
+
+	missing := []string{}
+	for variable := range n.terraformVariables() {
+		if !declared[variable] {
+			missing = append(missing, variable)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("the terraform at %s does not declare the variables %s, the gitops template does not support other repository and team names", tfEntrypoint, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// GetGitNames reads the --git-name-prefix and name flags of cmd, falling back to the kubefirst
+// config, checks the prefixed names and stores the flags in the config for destroy
+func GetGitNames(cmd *cobra.Command) (GitNames, error) {
+	flags := map[string]string{}
+	for _, name := range []string{"git-name-prefix", "gitops-repo-name", "metaphor-repo-name", "admin-team-name", "developer-team-name"} {
+		value, err := getConfigurableFlag(cmd, name)
+		if err != nil {
+			return GitNames{}, err
+		}
+		flags[name] = value
+	}
+
+	prefix := flags["git-name-prefix"]
+	gitNames := GitNames{
+		Prefix:        prefix,
+		GitopsRepo:    prefix + flags["gitops-repo-name"],
+		MetaphorRepo:  prefix + flags["metaphor-repo-name"],
+		AdminTeam:     prefix + flags["admin-team-name"],
+		DeveloperTeam: prefix + flags["developer-team-name"],
+	}
+	err := validateGitNames(gitNames.Repositories(), gitNames.Teams())
+	if err != nil {
+		return GitNames{}, err
+	}
+
+	for name, value := range flags {
+		viper.Set(fmt.Sprintf("flags.%s", name), value)
+	}
+
+	return gitNames, nil
+}
+
+// GitNamesFromConfig returns the names stored in the kubefirst config by GetGitNames, the
+// default names are used for clusters created before the names could be chosen
+func GitNamesFromConfig() GitNames {
+	configName := func(key string, defaultName string) string {
+		name := viper.GetString(fmt.Sprintf("flags.%s", key))
+		if name == "" {
+			name = defaultName
+		}
+
+		return viper.GetString("flags.git-name-prefix") + name
+	}
+
+	return GitNames{
+		Prefix:        viper.GetString("flags.git-name-prefix"),
+		GitopsRepo:    configName("gitops-repo-name", DefaultGitopsRepoName),
+		MetaphorRepo:  configName("metaphor-repo-name", DefaultMetaphorRepoName),
+		AdminTeam:     configName("admin-team-name", DefaultAdminTeamName),
+		DeveloperTeam: configName("developer-team-name", DefaultDeveloperTeamName),
+	}
+}
+
+// gitNamePattern matches the names github and gitlab both accept for repositories and teams
+var gitNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// validateGitNames checks the prefixed repository and team names before anything is created
+func validateGitNames(repositoryNames []string, teamNames []string) error {
+	for _, names := range [][]string{repositoryNames, teamNames} {
+		seen := map[string]bool{}
+		for _, name := range names {
+			if !gitNamePattern.MatchString(name) {
+				return fmt.Errorf("%q is not a valid repository or team name - use letters, digits, '.', '-' and '_'", name)
+			}
+			if seen[strings.ToLower(name)] {
+				return fmt.Errorf("the name %q is used twice - repository and team names must be unique", name)
+			}
+			seen[strings.ToLower(name)] = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package utilities
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitNamesTerraformEnvs(t *testing.T) {
+	tests := []struct {
+		name     string
+		gitNames GitNames
+		want     map[string]string
+	}{
+		{
+			name:     "default names",
+			gitNames: DefaultGitNames,
+			want: map[string]string{
+				"TF_VAR_gitops_repo_name":    "gitops",
+				"TF_VAR_metaphor_repo_name":  "metaphor",
+				"TF_VAR_admin_team_name":     "admins",
+				"TF_VAR_developer_team_name": "developers",
+			},
+		},
+		{
+			name: "prefixed and renamed",
+			gitNames: GitNames{
+				Prefix:        "prod-",
+				GitopsRepo:    "prod-platform",
+				MetaphorRepo:  "prod-metaphor",
+				AdminTeam:     "prod-admins",
+				DeveloperTeam: "prod-engineers",
+			},
+			want: map[string]string{
+				"TF_VAR_gitops_repo_name":    "prod-platform",
+				"TF_VAR_metaphor_repo_name":  "prod-metaphor",
+				"TF_VAR_admin_team_name":     "prod-admins",
+				"TF_VAR_developer_team_name": "prod-engineers",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.gitNames.TerraformEnvs()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TerraformEnvs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitNamesCheckTerraformVariables(t *testing.T) {
+	declarations := `variable "gitops_repo_name" {
+  type    = string
+  default = "gitops"
+}
+
+variable "metaphor_repo_name" {
+  default = "metaphor"
+}
+
+  variable "admin_team_name" {}
+`
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr bool
+	}{
+		{
+			name: "every variable declared",
+			files: map[string]string{
+				"variables.tf": declarations,
+				"teams.tf":     "variable \"developer_team_name\" {\n  default = \"developers\"\n}\n",
+			},
+		},
+		{
+			name:    "variable missing",
+			files:   map[string]string{"variables.tf": declarations},
+			wantErr: true,
+		},
+		{
+			name: "only referenced, not declared",
+			files: map[string]string{
+				"variables.tf": declarations,
+				"main.tf":      "module \"developers\" {\n  team_name = var.developer_team_name\n}\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "declared in a nested module",
+			files: map[string]string{
+				"variables.tf":                 declarations,
+				"modules/team/variables.tf":    "variable \"developer_team_name\" {}\n",
+				"modules/team/description.txt": "",
+			},
+			wantErr: true,
+		},
+		{
+			name: "declared outside a terraform file",
+			files: map[string]string{
+				"variables.tf": declarations,
+				"README.md":    "variable \"developer_team_name\" {}\n",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "no terraform",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tfEntrypoint := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(tfEntrypoint, name)
+				err := os.MkdirAll(filepath.Dir(path), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(path, []byte(content), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := DefaultGitNames.CheckTerraformVariables(tfEntrypoint)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckTerraformVariables() error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
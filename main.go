@@ -10,6 +10,7 @@ import (
 	"fmt"
 	stdLog "log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -22,6 +23,7 @@ import (
 	"github.com/kubefirst/runtime/configs"
 	"github.com/kubefirst/runtime/pkg"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 func main() {
@@ -40,6 +42,15 @@ func main() {
 		}
 	}
 
+	// the interactive terminal is replaced with plain output in ci, when stdout is
+	// not a terminal, and when a structured output format is requested
+	outputFormat, isCI := parseOutputArgs(argsWithProg)
+	if err := progress.SetOutputFormat(outputFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	usePlainProgress := isCI || progress.IsStructuredOutput() || !term.IsTerminal(int(os.Stdout.Fd()))
+
 	now := time.Now()
 	epoch := now.Unix()
 
@@ -102,15 +113,46 @@ func main() {
 	}
 
 	if canRunBubbleTea {
-		progress.InitializeProgressTerminal()
+		if usePlainProgress {
+			progress.InitializePlainProgress(progress.MessageWriter())
+		} else {
+			progress.InitializeProgressTerminal()
+		}
 
 		go func() {
 			cmd.Execute()
 		}()
 
 		progress.Progress.Run()
+		progress.CloseEventsFile()
+
+		if code := progress.ExitCode(); code != 0 {
+			logFileObj.Close()
+			os.Exit(code)
+		}
 	} else {
 		cmd.Execute()
+		progress.CloseEventsFile()
+	}
+}
+
+// parseOutputArgs reads the --output and --ci flags before cobra parses them, since the
+// progress backend has to be selected before any command runs. commands that write a file
+// take its path with --file so --output is always the format
+func parseOutputArgs(args []string) (string, bool) {
+	outputFormat := progress.OutputText
+	isCI := false
+
+	for i, arg := range args {
+		switch {
+		case arg == "--output" && i+1 < len(args):
+			outputFormat = args[i+1]
+		case strings.HasPrefix(arg, "--output="):
+			outputFormat = strings.TrimPrefix(arg, "--output=")
+		case arg == "--ci" || arg == "--ci=true":
+			isCI = true
+		}
 	}
 
+	return outputFormat, isCI
 }